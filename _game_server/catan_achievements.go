@@ -0,0 +1,114 @@
+package _game_server
+
+//------------------------------------------------------------------------------------------------------
+// Achievements (Longest Road, Largest Army)
+/*
+	Both trophies are worth 2 points and are recalculated after every action
+	that can change them:
+	- Nobody holds a trophy below its minimum (5 roads / 3 knights).
+	- The holder keeps the trophy as long as nobody has strictly more,
+	  a tie with the holder never takes it away.
+	- Without a (qualifying) holder, a single leader takes the trophy.
+	- If the holder's road is broken and several other players now tie for
+	  the longest road, the trophy is set aside until one of them leads alone.
+*/
+
+type TrophyType string
+
+const (
+	TrophyLongestRoad TrophyType = "longest_road"
+	TrophyLargestArmy TrophyType = "largest_army"
+)
+
+const (
+	trophyPoints   = 2
+	minLongestRoad = 5
+	minLargestArmy = 3
+)
+
+type Trophy struct {
+	OwnerID int // -1 if nobody holds it
+	Size    int // Road length / knights of the owner
+}
+
+// TrophyChange is the Data of an EventTrophyChanged
+type TrophyChange struct {
+	Trophy     TrophyType
+	OldOwnerID int
+	NewOwnerID int
+	Size       int
+}
+
+func newTrophy() Trophy {
+	return Trophy{OwnerID: -1}
+}
+
+// RecalculateAchievements updates road lengths and both trophies, moving the points
+func (g *GameSavestate) RecalculateAchievements() {
+	roads := make(map[int]int)
+	knights := make(map[int]int)
+	for id, p := range g.Players {
+		p.LongestRoad = p.GetLongestRoad(g.Board)
+		roads[id] = p.LongestRoad
+		knights[id] = p.KnightsPlayed
+	}
+
+	g.awardTrophy(TrophyLongestRoad, &g.LongestRoadTrophy, roads, minLongestRoad)
+	g.awardTrophy(TrophyLargestArmy, &g.LargestArmyTrophy, knights, minLargestArmy)
+}
+
+func (g *GameSavestate) awardTrophy(kind TrophyType, trophy *Trophy, sizes map[int]int, minimum int) {
+	// 1. Find the leading players
+	best := 0
+	for _, size := range sizes {
+		if size > best {
+			best = size
+		}
+	}
+	leaders := []int{}
+	for id, size := range sizes {
+		if size == best {
+			leaders = append(leaders, id)
+		}
+	}
+
+	// 2. Decide the new owner
+	newOwner := -1
+	if best >= minimum {
+		if trophy.OwnerID != -1 && sizes[trophy.OwnerID] == best {
+			newOwner = trophy.OwnerID
+		} else if len(leaders) == 1 {
+			newOwner = leaders[0]
+		}
+	}
+
+	// 3. Move the points and report the change
+	oldOwner := trophy.OwnerID
+	trophy.OwnerID = newOwner
+	trophy.Size = 0
+	if newOwner != -1 {
+		trophy.Size = sizes[newOwner]
+	}
+	if oldOwner == newOwner {
+		return
+	}
+
+	if oldOwner != -1 {
+		g.Players[oldOwner].Points -= trophyPoints
+	}
+	if newOwner != -1 {
+		g.Players[newOwner].Points += trophyPoints
+	}
+	g.emit(EventTrophyChanged, newOwner, TrophyChange{
+		Trophy:     kind,
+		OldOwnerID: oldOwner,
+		NewOwnerID: newOwner,
+		Size:       trophy.Size,
+	})
+}
+
+// afterAction runs the bookkeeping every successful action needs
+func (g *GameSavestate) afterAction() {
+	g.RecalculateAchievements()
+	g.checkVictory()
+}
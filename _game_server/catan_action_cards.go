@@ -0,0 +1,81 @@
+package _game_server
+
+import "fmt"
+
+//------------------------------------------------------------------------------------------------------
+// Action card lifecycle
+/*
+	Every card remembers the turn it was bought in. A card cannot be played in
+	that same turn, only one card may be played per turn and a played card
+	leaves the hand. Victory point cards are never played: they stay hidden
+	in the hand and count towards the player's total points.
+*/
+
+type HeldCard struct {
+	Card       ActionCard
+	BoughtTurn int // GameSavestate.Turn the card was bought in
+}
+
+func (c ActionCard) String() string {
+	switch c {
+	case Knight:
+		return "knight"
+	case Monopoly:
+		return "monopoly"
+	case VictoryPoint:
+		return "victory point"
+	case RoadBuilding:
+		return "road building"
+	case YearOfPlenty:
+		return "year of plenty"
+	}
+	return fmt.Sprintf("card(%d)", int(c))
+}
+
+// playableCard returns the index of a card of the type the player may play this turn
+func (p *Player) playableCard(card ActionCard, turn int) (int, error) {
+	if card == VictoryPoint {
+		return -1, fmt.Errorf("victory point cards are not played, they count automatically")
+	}
+	if p.PlayedCardThisTurn {
+		return -1, fmt.Errorf("only one development card may be played per turn")
+	}
+
+	boughtThisTurn := false
+	for i, held := range p.ActionCards {
+		if held.Card != card {
+			continue
+		}
+		if held.BoughtTurn < turn {
+			return i, nil
+		}
+		boughtThisTurn = true
+	}
+
+	if boughtThisTurn {
+		return -1, fmt.Errorf("a %v card cannot be played in the turn it was bought", card)
+	}
+	return -1, fmt.Errorf("you do not hold a %v card", card)
+}
+
+// discardPlayedCard removes the played card from the hand
+func (p *Player) discardPlayedCard(index int) {
+	p.ActionCards = append(p.ActionCards[:index], p.ActionCards[index+1:]...)
+	p.PlayedCardThisTurn = true
+}
+
+// VictoryPointCards counts the hidden victory point cards in the hand
+func (p *Player) VictoryPointCards() int {
+	count := 0
+	for _, held := range p.ActionCards {
+		if held.Card == VictoryPoint {
+			count++
+		}
+	}
+	return count
+}
+
+// TotalPoints are the public points plus the hidden victory point cards
+func (p *Player) TotalPoints() int {
+	return p.Points + p.VictoryPointCards()
+}
@@ -0,0 +1,127 @@
+package _game_server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Seat tokens
+/*
+	A seat token is handed out when a player joins a table and proves which
+	seat the bearer sits on. It is "<game ID>.<player ID>.<signature>", the
+	signature is an HMAC-SHA256 with the server secret over the game ID, the
+	player ID and the random key of the table. The key keeps tokens of a
+	deleted table from working on a later table with the same ID.
+
+	Whoever creates a table gets a creator token, it carries CreatorID
+	instead of a seat and only allows deleting the table.
+*/
+
+var ErrInvalidToken = errors.New("missing or invalid seat token")
+
+// CreatorID is the player ID in the token of the table creator, no seat has it
+const CreatorID = -1
+
+// Environment variable with the token secret, a random secret is used if it is not set
+const TokenSecretEnv = "CATAN_TOKEN_SECRET"
+
+type TokenSigner struct {
+	secret []byte
+}
+
+func NewTokenSigner(secret []byte) *TokenSigner {
+	return &TokenSigner{secret: secret}
+}
+
+// File in the data directory (see DataDirEnv) that keeps the secret if TokenSecretEnv is not set
+const tokenSecretFile = "token-secret"
+
+// LoadTokenSigner uses the secret from TokenSecretEnv. Without it the secret is
+// kept in dataDir, the tokens of restored games then stay valid after a restart.
+// Without both a random secret is used, it is lost with the process.
+func LoadTokenSigner(dataDir string) (*TokenSigner, error) {
+	if secret := os.Getenv(TokenSecretEnv); secret != "" {
+		return NewTokenSigner([]byte(secret)), nil
+	}
+	if dataDir == "" {
+		return NewTokenSigner([]byte(randomKey())), nil
+	}
+
+	// 1. Reuse the secret of an earlier run
+	path := filepath.Join(dataDir, tokenSecretFile)
+	secret, err := os.ReadFile(path)
+	if err == nil && len(secret) > 0 {
+		return NewTokenSigner(secret), nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// 2. First run: save a new one before any token is issued
+	secret = []byte(randomKey())
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(secret); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	return NewTokenSigner(secret), nil
+}
+
+// randomKey returns 32 random bytes, hex encoded
+func randomKey() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(key)
+}
+
+func (s *TokenSigner) signature(gameID, playerID int, tableKey string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%d.%d.%s", gameID, playerID, tableKey)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Issue returns the token of a seat
+func (s *TokenSigner) Issue(gameID, playerID int, tableKey string) string {
+	return fmt.Sprintf("%d.%d.%s", gameID, playerID, s.signature(gameID, playerID, tableKey))
+}
+
+// Verify checks a token of the table and returns the player ID it was issued to
+func (s *TokenSigner) Verify(token string, gameID int, tableKey string) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidToken
+	}
+	tokenGameID, err1 := strconv.Atoi(parts[0])
+	playerID, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || tokenGameID != gameID {
+		return 0, ErrInvalidToken
+	}
+
+	expected := s.signature(gameID, playerID, tableKey)
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return 0, ErrInvalidToken
+	}
+	return playerID, nil
+}
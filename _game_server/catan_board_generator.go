@@ -0,0 +1,224 @@
+package _game_server
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Board generation
+
+// Standard Catan Resource Distribution (None is the Desert)
+var standardResources = []Resource{
+	None,
+	Wood, Wood, Wood, Wood,
+	Wheat, Wheat, Wheat, Wheat,
+	Sheep, Sheep, Sheep, Sheep,
+	Rock, Rock, Rock,
+	Clay, Clay, Clay,
+}
+
+// Standard Catan Numbers (excluding 7 for Desert)
+var standardNumbers = []int{2, 3, 3, 4, 4, 5, 5, 6, 6, 8, 8, 9, 9, 10, 10, 11, 11, 12}
+
+// Official number token order (A to R), laid out along the spiral
+var spiralNumbers = []int{5, 2, 6, 3, 8, 10, 9, 12, 11, 4, 8, 10, 9, 4, 5, 6, 3, 11}
+
+type BoardOptions struct {
+	Seed      int64
+	Shape     []HexCoord // Defaults to the standard 3-4-5-4-3 board
+	Resources []Resource // One tile per hex, None is a Desert
+	Numbers   []int      // One token per non Desert hex, nil for the standard tokens (in spiral order with SpiralNumbers)
+	Harbors   []Resource // Harbor types (None = generic 3:1), empty for no harbors
+
+	// Place the tokens in the given order along a spiral from an outer corner
+	// instead of shuffling them (the official layout with the default numbers)
+	SpiralNumbers bool
+
+	NoAdjacentRedNumbers  bool // 6 and 8 never touch each other
+	NoAdjacentSameNumbers bool // Neighbouring hexes never share a number
+	MaxResourcePips       int  // Cap on the summed pips of one resource, 0 = no cap
+
+	RandomHarbors bool // Shuffle the harbor types and shift them along the coast
+
+	MaxAttempts int // Shuffles to try before giving up
+}
+
+// DefaultBoardOptions returns a balanced standard board for the seed
+func DefaultBoardOptions(seed int64) BoardOptions {
+	return BoardOptions{
+		Seed:                  seed,
+		Shape:                 StandardShape(),
+		Resources:             standardResources,
+		Harbors:               standardHarbors,
+		NoAdjacentRedNumbers:  true,
+		NoAdjacentSameNumbers: true,
+		MaxResourcePips:       0,
+		MaxAttempts:           1000,
+	}
+}
+
+// Pips is the number of dice combinations (out of 36) that roll the number
+func Pips(number int) int {
+	if number < 2 || number > 12 || number == 7 {
+		return 0
+	}
+	return 6 - abs(7-number)
+}
+
+// GenerateBoard shuffles tiles and tokens until all balance constraints hold.
+// The same options (including the seed) always produce the same board.
+func GenerateBoard(opts BoardOptions) (*Board, error) {
+	if opts.Shape == nil {
+		opts.Shape = StandardShape()
+	}
+	if opts.Resources == nil {
+		opts.Resources = standardResources
+	}
+	if opts.Numbers == nil {
+		opts.Numbers = standardNumbers
+		if opts.SpiralNumbers {
+			opts.Numbers = spiralNumbers
+		}
+	}
+	if opts.Harbors == nil {
+		opts.Harbors = standardHarbors
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+
+	// 1. Validate the tile and token counts
+	if len(opts.Resources) != len(opts.Shape) {
+		return nil, fmt.Errorf("%d resource tiles for %d hexes", len(opts.Resources), len(opts.Shape))
+	}
+	deserts := 0
+	for _, res := range opts.Resources {
+		if res == None {
+			deserts++
+		}
+	}
+	if len(opts.Numbers) != len(opts.Shape)-deserts {
+		return nil, fmt.Errorf("%d number tokens for %d producing hexes", len(opts.Numbers), len(opts.Shape)-deserts)
+	}
+
+	board := NewBoard(opts.Shape)
+	board.Seed = opts.Seed
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	tiles := append([]Resource{}, opts.Resources...)
+	tokens := append([]int{}, opts.Numbers...)
+	values := make([]int, len(tiles))
+
+	for attempt := 0; attempt < opts.MaxAttempts; attempt++ {
+		// 2. Shuffle the tiles
+		rng.Shuffle(len(tiles), func(i, j int) {
+			tiles[i], tiles[j] = tiles[j], tiles[i]
+		})
+
+		// 3. Lay out the tokens (in hex ID order or along the spiral)
+		order := make([]int, 0, len(tiles))
+		if opts.SpiralNumbers {
+			order = board.spiralOrder(rng.Intn(6))
+		} else {
+			rng.Shuffle(len(tokens), func(i, j int) {
+				tokens[i], tokens[j] = tokens[j], tokens[i]
+			})
+			for id := range tiles {
+				order = append(order, id)
+			}
+		}
+
+		next := 0
+		for _, id := range order {
+			values[id] = 0
+			if tiles[id] != None {
+				values[id] = tokens[next]
+				next++
+			}
+		}
+
+		// 4. Check the balance constraints, otherwise try again
+		if board.isBalanced(tiles, values, opts) {
+			robberPlaced := false
+			for id := 0; id < len(tiles); id++ {
+				hex := board.Hexes[id]
+				hex.ResourceType = tiles[id]
+				hex.Value = values[id]
+				// The Robber starts on the first Desert
+				hex.HasRobber = tiles[id] == None && !robberPlaced
+				robberPlaced = robberPlaced || hex.HasRobber
+			}
+
+			// 5. Place the harbors along the coast
+			harbors := append([]Resource{}, opts.Harbors...)
+			offset := 0
+			if opts.RandomHarbors {
+				rng.Shuffle(len(harbors), func(i, j int) {
+					harbors[i], harbors[j] = harbors[j], harbors[i]
+				})
+				offset = rng.Intn(len(board.CoastalEdges()))
+			}
+			if err := board.PlaceHarbors(harbors, offset); err != nil {
+				return nil, err
+			}
+			if err := board.CheckTopology(); err != nil {
+				return nil, err
+			}
+			return board, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no balanced board found for seed %d after %d attempts", opts.Seed, opts.MaxAttempts)
+}
+
+// spiralOrder returns the Hex IDs from the outer ring inwards, starting at
+// the corner hex in direction startDir
+func (b *Board) spiralOrder(startDir int) []int {
+	radius := 0
+	for _, hex := range b.Hexes {
+		if d := hex.Coord.Distance(HexCoord{}); d > radius {
+			radius = d
+		}
+	}
+
+	order := []int{}
+	for _, coord := range HexSpiral(HexCoord{}, radius, startDir) {
+		if hex, ok := b.HexAt(coord); ok {
+			order = append(order, hex.ID)
+		}
+	}
+	return order
+}
+
+func (b *Board) isBalanced(tiles []Resource, values []int, opts BoardOptions) bool {
+	// Neighbouring numbers
+	for id, value := range values {
+		if value == 0 {
+			continue
+		}
+		for _, otherID := range b.NeighborHexes(id) {
+			other := values[otherID]
+			if opts.NoAdjacentRedNumbers && (value == 6 || value == 8) && (other == 6 || other == 8) {
+				return false
+			}
+			if opts.NoAdjacentSameNumbers && value == other {
+				return false
+			}
+		}
+	}
+
+	// Pips per resource
+	if opts.MaxResourcePips > 0 {
+		pips := make(map[Resource]int)
+		for id, res := range tiles {
+			pips[res] += Pips(values[id])
+		}
+		for res, total := range pips {
+			if res != None && total > opts.MaxResourcePips {
+				return false
+			}
+		}
+	}
+	return true
+}
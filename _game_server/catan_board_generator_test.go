@@ -0,0 +1,82 @@
+package _game_server
+
+import (
+	"reflect"
+	"testing"
+)
+
+// layout lists resource and number of every hex in ID order
+func layout(b *Board) [][2]int {
+	hexes := make([][2]int, len(b.Hexes))
+	for id, hex := range b.Hexes {
+		hexes[id] = [2]int{int(hex.ResourceType), hex.Value}
+	}
+	return hexes
+}
+
+// checkNumbers fails if two neighbouring hexes share a number or both roll a 6 or 8
+func checkNumbers(t *testing.T, b *Board) {
+	t.Helper()
+	red := func(value int) bool { return value == 6 || value == 8 }
+	for id, hex := range b.Hexes {
+		for _, neighborID := range b.NeighborHexes(id) {
+			neighbor := b.Hexes[neighborID]
+			if hex.Value == 0 || neighbor.Value == 0 {
+				continue
+			}
+			if hex.Value == neighbor.Value || (red(hex.Value) && red(neighbor.Value)) {
+				t.Errorf("seed %d: hex %d (%d) touches hex %d (%d)", b.Seed, id, hex.Value, neighborID, neighbor.Value)
+			}
+		}
+	}
+}
+
+func TestGenerateBoardSameSeed(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		first, err := GenerateBoard(DefaultBoardOptions(seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := GenerateBoard(DefaultBoardOptions(seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(layout(first), layout(second)) {
+			t.Errorf("seed %d: two different boards", seed)
+		}
+		checkNumbers(t, first)
+	}
+
+	one, _ := GenerateBoard(DefaultBoardOptions(1))
+	two, _ := GenerateBoard(DefaultBoardOptions(2))
+	if reflect.DeepEqual(layout(one), layout(two)) {
+		t.Error("seeds 1 and 2 generate the same board")
+	}
+}
+
+func TestGenerateBoardSpiral(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		opts := DefaultBoardOptions(seed)
+		opts.SpiralNumbers = true
+		board, err := GenerateBoard(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkNumbers(t, board)
+
+		// The tokens follow the official order along the spiral from one of the six corners
+		found := false
+		for startDir := 0; startDir < 6 && !found; startDir++ {
+			tokens := []int{}
+			for _, id := range board.spiralOrder(startDir) {
+				if value := board.Hexes[id].Value; value != 0 {
+					tokens = append(tokens, value)
+				}
+			}
+			found = reflect.DeepEqual(tokens, spiralNumbers)
+		}
+		if !found {
+			t.Errorf("seed %d: the tokens are not in spiral order", seed)
+		}
+	}
+}
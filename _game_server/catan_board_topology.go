@@ -0,0 +1,187 @@
+package _game_server
+
+import (
+	"fmt"
+	"sort"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Board topology
+/*
+	The topology is derived from the axial coordinates of the hexes (see
+	catan_hex_grid.go), so any board shape works. Shared corners and sides of
+	neighbouring hexes have the same canonical coordinate, so collecting them
+	gives the 54 corners and 72 edges of the standard board.
+	Corner IDs are assigned top to bottom, left to right, Edge IDs are sorted
+	by the IDs of their two corners.
+*/
+
+// NewBoard creates an empty board (no resources, no numbers) for the given shape.
+// The index in shape becomes the Hex ID.
+func NewBoard(shape []HexCoord) *Board {
+	board := &Board{
+		Hexes:   make(map[int]*Hex),
+		Corners: make(map[int]*Corner),
+		Edges:   make(map[int]*Edge),
+		Harbors: make(map[int]*Harbor),
+	}
+	for id, coord := range shape {
+		board.Hexes[id] = &Hex{ID: id, Coord: coord, ResourceType: None}
+	}
+	board.SetupAdjacency()
+	return board
+}
+
+// SetupAdjacency builds every Corner and Edge of the board and links them together
+func (b *Board) SetupAdjacency() {
+	b.Corners = make(map[int]*Corner)
+	b.Edges = make(map[int]*Edge)
+	b.hexAt = make(map[HexCoord]int)
+
+	// 1. Collect the corners and sides of every hex
+	touching := make(map[CornerCoord][]int)
+	sides := make(map[EdgeCoord]bool)
+	for id := 0; id < len(b.Hexes); id++ {
+		hex := b.Hexes[id]
+		b.hexAt[hex.Coord] = id
+		for _, corner := range hex.Coord.Corners() {
+			touching[corner] = append(touching[corner], id)
+		}
+		for _, side := range hex.Coord.Edges() {
+			sides[side] = true
+		}
+	}
+
+	// 2. Assign Corner IDs in reading order
+	coords := make([]CornerCoord, 0, len(touching))
+	for c := range touching {
+		coords = append(coords, c)
+	}
+	sort.Slice(coords, func(i, j int) bool {
+		xi, yi := coords[i].lattice()
+		xj, yj := coords[j].lattice()
+		if yi != yj {
+			return yi < yj
+		}
+		return xi < xj
+	})
+
+	cornerAt := make(map[CornerCoord]int)
+	for id, c := range coords {
+		cornerAt[c] = id
+		b.Corners[id] = &Corner{
+			ID:            id,
+			Coord:         c,
+			OwnerID:       -1,
+			AdjacentHexes: touching[c],
+		}
+	}
+
+	// 3. Every side of a hex is an Edge (shared sides only once)
+	edges := make([]*Edge, 0, len(sides))
+	for side := range sides {
+		ends := side.Corners()
+		a, c := cornerAt[ends[0]], cornerAt[ends[1]]
+		if a > c {
+			a, c = c, a
+		}
+		edges = append(edges, &Edge{Coord: side, OwnerID: -1, Corners: [2]int{a, c}})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Corners[0] != edges[j].Corners[0] {
+			return edges[i].Corners[0] < edges[j].Corners[0]
+		}
+		return edges[i].Corners[1] < edges[j].Corners[1]
+	})
+
+	// 4. Link Edges and Corners in both directions
+	for id, edge := range edges {
+		edge.ID = id
+		b.Edges[id] = edge
+
+		first, second := b.Corners[edge.Corners[0]], b.Corners[edge.Corners[1]]
+		first.AdjacentCorners = append(first.AdjacentCorners, second.ID)
+		first.AdjacentEdges = append(first.AdjacentEdges, id)
+		second.AdjacentCorners = append(second.AdjacentCorners, first.ID)
+		second.AdjacentEdges = append(second.AdjacentEdges, id)
+	}
+}
+
+// CheckTopology verifies that the Hex/Corner/Edge graph is complete and consistent
+func (b *Board) CheckTopology() error {
+	for _, corner := range b.Corners {
+		if len(corner.AdjacentHexes) < 1 || len(corner.AdjacentHexes) > 3 {
+			return fmt.Errorf("corner %d touches %d hexes", corner.ID, len(corner.AdjacentHexes))
+		}
+		for _, hexID := range corner.AdjacentHexes {
+			if _, ok := b.Hexes[hexID]; !ok {
+				return fmt.Errorf("corner %d references unknown hex %d", corner.ID, hexID)
+			}
+		}
+		if len(corner.AdjacentCorners) != len(corner.AdjacentEdges) {
+			return fmt.Errorf("corner %d has %d neighbours but %d edges", corner.ID, len(corner.AdjacentCorners), len(corner.AdjacentEdges))
+		}
+		for _, edgeID := range corner.AdjacentEdges {
+			edge, ok := b.Edges[edgeID]
+			if !ok {
+				return fmt.Errorf("corner %d references unknown edge %d", corner.ID, edgeID)
+			}
+			if edge.Corners[0] != corner.ID && edge.Corners[1] != corner.ID {
+				return fmt.Errorf("edge %d does not touch corner %d", edgeID, corner.ID)
+			}
+		}
+	}
+
+	for _, edge := range b.Edges {
+		for _, cornerID := range edge.Corners {
+			if _, ok := b.Corners[cornerID]; !ok {
+				return fmt.Errorf("edge %d references unknown corner %d", edge.ID, cornerID)
+			}
+		}
+	}
+
+	// Euler's formula for a planar graph: V - E + F = 2 (F includes the outer face)
+	if len(b.Corners)-len(b.Edges)+len(b.Hexes)+1 != 2 {
+		return fmt.Errorf("board graph is not closed: %d corners, %d edges, %d hexes", len(b.Corners), len(b.Edges), len(b.Hexes))
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------------------------------
+// Hex queries by ID
+
+// HexAt returns the hex at the given coordinate, if it is on the board
+func (b *Board) HexAt(coord HexCoord) (*Hex, bool) {
+	id, ok := b.hexAt[coord]
+	if !ok {
+		return nil, false
+	}
+	return b.Hexes[id], true
+}
+
+// NeighborHexes returns the IDs of all hexes bordering the given hex
+func (b *Board) NeighborHexes(hexID int) []int {
+	list := []int{}
+	for _, coord := range b.Hexes[hexID].Coord.Neighbors() {
+		if hex, ok := b.HexAt(coord); ok {
+			list = append(list, hex.ID)
+		}
+	}
+	return list
+}
+
+// HexDistance returns the number of hex steps between two hexes
+func (b *Board) HexDistance(hexA int, hexB int) int {
+	return b.Hexes[hexA].Coord.Distance(b.Hexes[hexB].Coord)
+}
+
+// HexRingIDs returns the IDs of all hexes at exactly radius steps from a hex
+func (b *Board) HexRingIDs(centerID int, radius int) []int {
+	list := []int{}
+	for _, coord := range HexRing(b.Hexes[centerID].Coord, radius, East) {
+		if hex, ok := b.HexAt(coord); ok {
+			list = append(list, hex.ID)
+		}
+	}
+	return list
+}
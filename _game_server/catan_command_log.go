@@ -0,0 +1,313 @@
+package _game_server
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Command log
+/*
+	Every accepted action is appended to Commands as an immutable, numbered
+	Command (Seq starts at 1). Board, deck and dice all derive from the seed
+	and salt, so replaying the log on a new game from the same seed and salt
+	rebuilds the exact state, events included (see ReplayGame). The server only changes a game
+	through Execute, which is what makes the log complete. Replays keep the
+	Seq of every command, trade offers are identified by it.
+
+	Undo is a command as well: the log keeps the undone command and the undo,
+	the state goes back to a snapshot taken right before the undone command.
+	Only actions that reveal nothing new can be undone (no dice, no cards,
+	no steals), otherwise undo would let a player peek into the future. As
+	they draw nothing from the random source, the snapshot is the same state
+	a replay of the log without the undone command would build.
+	Event streams learn about it from an EventCommandUndone and should fetch
+	the state again.
+*/
+
+type CommandType string
+
+const (
+	CommandRoll             CommandType = "roll"
+	CommandDiscard          CommandType = "discard"
+	CommandMoveRobber       CommandType = "move_robber"
+	CommandBuildSettlement  CommandType = "build_settlement" // Upgrades to a city if the corner has the player's settlement
+	CommandBuildCity        CommandType = "build_city"
+	CommandBuildRoad        CommandType = "build_road"
+	CommandMaritimeTrade    CommandType = "maritime_trade"
+	CommandProposeTrade     CommandType = "propose_trade"
+	CommandAcceptTrade      CommandType = "accept_trade"
+	CommandBuyActionCard    CommandType = "buy_action_card"
+	CommandPlayKnight       CommandType = "play_knight"
+	CommandPlayYearOfPlenty CommandType = "play_year_of_plenty"
+	CommandPlayMonopoly     CommandType = "play_monopoly"
+	CommandPlayRoadBuilding CommandType = "play_road_building"
+	CommandEndTurn          CommandType = "end_turn"
+	CommandUndo             CommandType = "undo"
+)
+
+// Commands that can be taken back by the player who issued them
+var undoableCommands = map[CommandType]bool{
+	CommandBuildSettlement: true,
+	CommandBuildCity:       true,
+	CommandBuildRoad:       true,
+	CommandMaritimeTrade:   true,
+	CommandProposeTrade:    true,
+}
+
+var ErrNothingToUndo = errors.New("there is no action of yours to undo")
+
+// Command is one accepted action, only the arguments of its type are set
+type Command struct {
+	Seq      int
+	Type     CommandType
+	PlayerID int
+
+	CornerID  int         `json:",omitempty"`
+	EdgeID    int         `json:",omitempty"`
+	EdgeIDs   []int       `json:",omitempty"` // Road Building
+	HexID     int         `json:",omitempty"`
+	VictimID  int         `json:",omitempty"`
+	Resources ResourceMap `json:",omitempty"` // Discarded cards
+	Resource  Resource    `json:",omitempty"` // Monopoly
+	Picks     []Resource  `json:",omitempty"` // Year of Plenty
+	Give      Resource    `json:",omitempty"` // Maritime trade
+	Take      Resource    `json:",omitempty"`
+	Ratio     int         `json:",omitempty"`
+	Offer     *TradeOffer `json:",omitempty"` // Proposed trade
+	OfferID   int         `json:",omitempty"` // Accepted trade
+}
+
+// CommandResult holds what the action returned, only the fields of the command type are set
+type CommandResult struct {
+	Roll       DiceRoll
+	Production ProductionReport
+	Stolen     Resource   // Robber and Knight
+	Taken      int        // Monopoly
+	Card       ActionCard // Bought card
+	OfferID    int
+}
+
+// Execute runs a command and appends it to the log if the action was accepted
+func (g *GameSavestate) Execute(cmd Command) (CommandResult, error) {
+	cmd.Seq = len(g.Commands) + 1
+	result, err := g.apply(cmd)
+	if err != nil {
+		return result, err
+	}
+	g.Commands = append(g.Commands, cmd)
+	return result, nil
+}
+
+// apply runs the action of a command, without logging it. Undoable commands
+// leave a snapshot of the state before them on the undo stack.
+func (g *GameSavestate) apply(cmd Command) (CommandResult, error) {
+	var snapshot *GameSavestate
+	if undoableCommands[cmd.Type] {
+		snapshot = g.clone()
+	}
+	result, err := g.run(cmd)
+	if err != nil {
+		return result, err
+	}
+
+	switch {
+	case snapshot != nil:
+		g.undoStack = append(g.undoStack, undoPoint{Command: cmd, State: snapshot})
+	case cmd.Type != CommandUndo:
+		g.undoStack = nil // Nothing before this command can be undone anymore
+	}
+	return result, nil
+}
+
+// run dispatches a command to its action
+func (g *GameSavestate) run(cmd Command) (CommandResult, error) {
+	var result CommandResult
+	var err error
+
+	switch cmd.Type {
+	case CommandRoll:
+		result.Roll, result.Production, err = g.Roll(cmd.PlayerID)
+	case CommandDiscard:
+		err = g.Discard(cmd.PlayerID, cmd.Resources)
+	case CommandMoveRobber:
+		result.Stolen, err = g.MoveRobber(cmd.PlayerID, cmd.HexID, cmd.VictimID)
+	case CommandBuildSettlement:
+		err = g.BuildSettlementOrCity(cmd.PlayerID, cmd.CornerID)
+	case CommandBuildCity:
+		err = g.BuildCity(cmd.PlayerID, cmd.CornerID)
+	case CommandBuildRoad:
+		err = g.BuildRoad(cmd.PlayerID, cmd.EdgeID)
+	case CommandMaritimeTrade:
+		err = g.MaritimeTrade(cmd.PlayerID, cmd.Give, cmd.Take, cmd.Ratio)
+	case CommandProposeTrade:
+		if cmd.Offer == nil {
+			return result, fmt.Errorf("missing trade offer")
+		}
+		offer := *cmd.Offer
+		offer.SenderID = cmd.PlayerID
+		result.OfferID = cmd.Seq
+		err = g.ProposeTrade(cmd.Seq, offer)
+	case CommandAcceptTrade:
+		err = g.AcceptTrade(cmd.PlayerID, cmd.OfferID)
+	case CommandBuyActionCard:
+		result.Card, err = g.BuyActionCard(cmd.PlayerID)
+	case CommandPlayKnight:
+		result.Stolen, err = g.PlayKnight(cmd.PlayerID, cmd.HexID, cmd.VictimID)
+	case CommandPlayYearOfPlenty:
+		if len(cmd.Picks) != 2 {
+			return result, fmt.Errorf("year of plenty takes two resources")
+		}
+		err = g.PlayYearOfPlenty(cmd.PlayerID, cmd.Picks[0], cmd.Picks[1])
+	case CommandPlayMonopoly:
+		result.Taken, err = g.PlayMonopoly(cmd.PlayerID, cmd.Resource)
+	case CommandPlayRoadBuilding:
+		err = g.PlayRoadBuilding(cmd.PlayerID, cmd.EdgeIDs)
+	case CommandEndTurn:
+		err = g.EndTurn(cmd.PlayerID)
+	case CommandUndo:
+		err = g.undo(cmd)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd.Type)
+	}
+	return result, err
+}
+
+// ReplayGame derives a game from its seed, salt and command log
+func ReplayGame(gameID int, seed int64, salt int64, playerIDs []int, rules Rules, log []Command) (*GameSavestate, error) {
+	game, err := NewGame(gameID, seed, salt, playerIDs, rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, cmd := range log {
+		if _, err := game.apply(cmd); err != nil {
+			return nil, fmt.Errorf("replaying command %d (%s): %w", cmd.Seq, cmd.Type, err)
+		}
+		game.Commands = append(game.Commands, cmd)
+	}
+	return game, nil
+}
+
+// Replay rebuilds the game from its seed and the first n commands of its log
+func (g *GameSavestate) Replay(n int) (*GameSavestate, error) {
+	if n < 0 || n > len(g.Commands) {
+		return nil, fmt.Errorf("the log has %d commands", len(g.Commands))
+	}
+	return ReplayGame(g.ID, g.Seed, g.Salt, g.TurnOrder, g.Rules, g.Commands[:n])
+}
+
+// effectiveCommands returns the log without undone commands and without the undos
+func effectiveCommands(log []Command) []Command {
+	effective := []Command{}
+	for _, cmd := range log {
+		if cmd.Type == CommandUndo {
+			if len(effective) > 0 {
+				effective = effective[:len(effective)-1]
+			}
+			continue
+		}
+		effective = append(effective, cmd)
+	}
+	return effective
+}
+
+// undo takes back the last action if the player issued it and it can be undone
+func (g *GameSavestate) undo(cmd Command) error {
+	if g.Phase == PhaseGameOver {
+		return ErrGameOver
+	}
+
+	// 1. Only the last action still in effect can be undone, it is the top of the undo stack.
+	// Once the turn passed on (e.g. after an opening road) it stays.
+	if cmd.PlayerID != g.CurrentPlayerID {
+		return ErrNothingToUndo
+	}
+	if len(g.undoStack) == 0 {
+		effective := effectiveCommands(g.Commands)
+		if len(effective) == 0 || effective[len(effective)-1].PlayerID != cmd.PlayerID {
+			return ErrNothingToUndo
+		}
+		return fmt.Errorf("%s can not be undone", effective[len(effective)-1].Type)
+	}
+	last := g.undoStack[len(g.undoStack)-1]
+	if last.Command.PlayerID != cmd.PlayerID {
+		return ErrNothingToUndo
+	}
+
+	// 2. Go back to the state before it, log, events and random source keep their history
+	restored := *last.State
+	restored.Commands = g.Commands
+	restored.Events = g.Events
+	restored.rng = g.rng
+	restored.undoStack = g.undoStack[:len(g.undoStack)-1]
+	*g = restored
+
+	g.emit(EventCommandUndone, cmd.PlayerID, CommandUndone{Seq: last.Command.Seq, Type: last.Command.Type})
+	return nil
+}
+
+// ViewFor returns the command as viewerID may see it, discarded cards stay private
+func (c Command) ViewFor(viewerID int) Command {
+	if c.Type == CommandDiscard && c.PlayerID != viewerID {
+		c.Resources = nil
+	}
+	return c
+}
+
+//------------------------------------------------------------------------------------------------------
+// Undo snapshots
+
+// undoPoint is an undoable command and the state right before it
+type undoPoint struct {
+	Command Command
+	State   *GameSavestate
+}
+
+// clone copies everything an action can change. The snapshot leaves out the log,
+// the events and the random source, undo keeps the current ones.
+func (g *GameSavestate) clone() *GameSavestate {
+	c := *g
+	c.Board = g.Board.clone()
+	c.Bank = &Bank{Resources: maps.Clone(g.Bank.Resources), ActionCards: append([]ActionCard(nil), g.Bank.ActionCards...)}
+	c.Players = make(map[int]*Player, len(g.Players))
+	for id, player := range g.Players {
+		c.Players[id] = player.clone()
+	}
+	c.DiceHistory = append([]DiceRoll(nil), g.DiceHistory...)
+	c.PendingDiscards = maps.Clone(g.PendingDiscards)
+	c.TradeOffers = maps.Clone(g.TradeOffers) // Offers are never changed, only added and removed
+	c.Events = nil
+	c.Commands = nil
+	c.rng = nil
+	c.undoStack = nil
+	return &c
+}
+
+// clone copies the pieces on the board, the layout and the harbors never change
+func (b *Board) clone() *Board {
+	c := *b
+	c.Hexes = make(map[int]*Hex, len(b.Hexes))
+	for id, hex := range b.Hexes {
+		copied := *hex
+		c.Hexes[id] = &copied
+	}
+	c.Corners = make(map[int]*Corner, len(b.Corners))
+	for id, corner := range b.Corners {
+		copied := *corner
+		c.Corners[id] = &copied
+	}
+	c.Edges = make(map[int]*Edge, len(b.Edges))
+	for id, edge := range b.Edges {
+		copied := *edge
+		c.Edges[id] = &copied
+	}
+	return &c
+}
+
+func (p *Player) clone() *Player {
+	c := *p
+	c.Resources = maps.Clone(p.Resources)
+	c.ActionCards = append([]HeldCard(nil), p.ActionCards...)
+	return &c
+}
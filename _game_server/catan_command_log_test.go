@@ -0,0 +1,182 @@
+package _game_server
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Test helpers
+
+// botCommand picks a random command for whoever has to act, many of them are rejected
+func botCommand(g *GameSavestate, r *rand.Rand) Command {
+	if g.Phase == PhaseInitialPlacement {
+		return nextOpeningCommand(g)
+	}
+	for _, playerID := range sortedIDs(g.PendingDiscards) {
+		discard := ResourceMap{}
+		for left := g.PendingDiscards[playerID]; left > 0; {
+			res := AllResources[r.Intn(len(AllResources))]
+			if g.Players[playerID].Resources[res] > discard[res] {
+				discard[res]++
+				left--
+			}
+		}
+		return Command{Type: CommandDiscard, PlayerID: playerID, Resources: discard}
+	}
+
+	playerID := g.CurrentPlayerID
+	switch g.Phase {
+	case PhaseRoll:
+		return Command{Type: CommandRoll, PlayerID: playerID}
+	case PhaseRobber:
+		hexID, victimID := r.Intn(len(g.Board.Hexes)), -1
+		if victims := g.ValidVictims(playerID, hexID); len(victims) > 0 {
+			victimID = victims[0]
+		}
+		return Command{Type: CommandMoveRobber, PlayerID: playerID, HexID: hexID, VictimID: victimID}
+	}
+
+	resource := func() Resource { return AllResources[r.Intn(len(AllResources))] }
+	switch r.Intn(10) {
+	case 0:
+		return Command{Type: CommandBuildRoad, PlayerID: playerID, EdgeID: r.Intn(len(g.Board.Edges))}
+	case 1:
+		return Command{Type: CommandBuildSettlement, PlayerID: playerID, CornerID: r.Intn(len(g.Board.Corners))}
+	case 2:
+		return Command{Type: CommandBuildCity, PlayerID: playerID, CornerID: r.Intn(len(g.Board.Corners))}
+	case 3:
+		return Command{Type: CommandMaritimeTrade, PlayerID: playerID, Give: resource(), Take: resource()}
+	case 4:
+		return Command{Type: CommandBuyActionCard, PlayerID: playerID}
+	case 5:
+		offer := TradeOffer{ReceiverID: g.TurnOrder[r.Intn(len(g.TurnOrder))], Give: ResourceMap{resource(): 1}, Receive: ResourceMap{resource(): 1}}
+		return Command{Type: CommandProposeTrade, PlayerID: playerID, Offer: &offer}
+	case 6:
+		for _, offerID := range sortedIDs(g.TradeOffers) {
+			return Command{Type: CommandAcceptTrade, PlayerID: g.TradeOffers[offerID].ReceiverID, OfferID: offerID}
+		}
+	case 7, 8:
+		return Command{Type: CommandUndo, PlayerID: playerID}
+	}
+	return Command{Type: CommandEndTurn, PlayerID: playerID}
+}
+
+// fingerprint encodes the state of the game without its log and events
+func fingerprint(t *testing.T, g *GameSavestate) string {
+	t.Helper()
+	data, err := json.Marshal(g.clone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+//------------------------------------------------------------------------------------------------------
+// Undo
+
+func TestUndoBuildRoad(t *testing.T) {
+	g, err := NewGame(1, 1, 0, []int{0, 1}, StandardRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	playOpening(t, g)
+	playerID := g.CurrentPlayerID
+	if _, err := g.Execute(Command{Type: CommandRoll, PlayerID: playerID}); err != nil {
+		t.Fatal(err)
+	}
+	if g.Phase != PhaseTradeBuild {
+		t.Fatalf("the first roll of seed 1 should not be a 7, phase is %s", g.Phase)
+	}
+	for res, amount := range Costs["road"] {
+		g.Bank.Resources[res] -= amount
+		g.Players[playerID].Resources[res] += amount
+	}
+	before := fingerprint(t, g)
+
+	// 1. Build the first road the board allows
+	built := false
+	for _, edgeID := range sortedIDs(g.Board.Edges) {
+		if _, err := g.Execute(Command{Type: CommandBuildRoad, PlayerID: playerID, EdgeID: edgeID}); err == nil {
+			built = true
+			break
+		}
+	}
+	if !built || fingerprint(t, g) == before {
+		t.Fatal("no road was built")
+	}
+
+	// 2. Only the builder can take it back
+	if _, err := g.Execute(Command{Type: CommandUndo, PlayerID: 1 - playerID}); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("undo by the other player: %v", err)
+	}
+	if _, err := g.Execute(Command{Type: CommandUndo, PlayerID: playerID}); err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint(t, g) != before {
+		t.Error("undo did not restore the state before the road")
+	}
+	if last := g.Events[len(g.Events)-1]; last.Type != EventCommandUndone {
+		t.Errorf("last event is %s", last.Type)
+	}
+
+	// 3. The roll before it reveals the dice and stays
+	if _, err := g.Execute(Command{Type: CommandUndo, PlayerID: playerID}); err == nil {
+		t.Error("the roll was undone")
+	}
+}
+
+func TestUndoAfterOpeningTurn(t *testing.T) {
+	g, err := NewGame(1, 1, 0, []int{0, 1}, StandardRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := g.Execute(nextOpeningCommand(g)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.CurrentPlayerID != 1 {
+		t.Fatalf("player %d places after the first opening road", g.CurrentPlayerID)
+	}
+	if _, err := g.Execute(Command{Type: CommandUndo, PlayerID: 0}); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("undo of the road after the turn passed: %v", err)
+	}
+	if g.CurrentPlayerID != 1 {
+		t.Errorf("the turn went back to player %d", g.CurrentPlayerID)
+	}
+}
+
+func TestUndoMatchesReplay(t *testing.T) {
+	for seed := int64(1); seed <= 3; seed++ {
+		g, err := NewGame(1, seed, 42, []int{0, 1, 2}, StandardRules())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := rand.New(rand.NewSource(seed))
+		for i := 0; i < 1500 && g.Phase != PhaseGameOver; i++ {
+			cmd := botCommand(g, r)
+			if _, err := g.Execute(cmd); err != nil || cmd.Type != CommandUndo {
+				continue
+			}
+			// Undo must leave the state a replay without the undone command builds
+			replayed, err := ReplayGame(g.ID, g.Seed, g.Salt, g.TurnOrder, g.Rules, effectiveCommands(g.Commands))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fingerprint(t, replayed) != fingerprint(t, g) {
+				t.Fatalf("seed %d: state after undo %d differs from the replay", seed, len(g.Commands))
+			}
+		}
+
+		replayed, err := g.Replay(len(g.Commands))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fingerprint(t, replayed) != fingerprint(t, g) {
+			t.Errorf("seed %d: replay of %d commands differs", seed, len(g.Commands))
+		}
+	}
+}
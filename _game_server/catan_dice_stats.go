@@ -0,0 +1,119 @@
+package _game_server
+
+import "math"
+
+//------------------------------------------------------------------------------------------------------
+// Dice statistics
+/*
+	Compares the rolled totals with the 2d6 distribution (pips / 36) using
+	Pearson's chi-square test. With 11 possible totals there are 10 degrees
+	of freedom. A PValue below 0.05 means the rolls would be unusual for fair
+	dice; with only a few rolls (expected count < 5 per total) the test is not
+	meaningful yet.
+*/
+
+type DiceTotalStats struct {
+	Total         int
+	Count         int
+	Frequency     float64 // Observed share of all rolls
+	Probability   float64 // Expected share for fair dice
+	ExpectedCount float64
+}
+
+type DiceStats struct {
+	Rolls            int
+	Totals           []DiceTotalStats // Totals 2 to 12
+	Faces            [2][6]int        // How often each die showed 1 to 6
+	ChiSquare        float64
+	DegreesOfFreedom int
+	PValue           float64
+}
+
+func ComputeDiceStats(history []DiceRoll) DiceStats {
+	stats := DiceStats{Rolls: len(history), DegreesOfFreedom: 10}
+
+	counts := make(map[int]int)
+	for _, roll := range history {
+		counts[roll.Total]++
+		stats.Faces[0][roll.Die1-1]++
+		stats.Faces[1][roll.Die2-1]++
+	}
+
+	for total := 2; total <= 12; total++ {
+		probability := float64(6-abs(7-total)) / 36
+		expected := probability * float64(len(history))
+
+		entry := DiceTotalStats{
+			Total:         total,
+			Count:         counts[total],
+			Probability:   probability,
+			ExpectedCount: expected,
+		}
+		if len(history) > 0 {
+			entry.Frequency = float64(counts[total]) / float64(len(history))
+			diff := float64(counts[total]) - expected
+			stats.ChiSquare += diff * diff / expected
+		}
+		stats.Totals = append(stats.Totals, entry)
+	}
+
+	stats.PValue = 1
+	if len(history) > 0 {
+		stats.PValue = chiSquarePValue(stats.ChiSquare, stats.DegreesOfFreedom)
+	}
+	return stats
+}
+
+// chiSquarePValue is the chance of a chi-square value at least this large for fair dice
+func chiSquarePValue(chiSquare float64, dof int) float64 {
+	return upperIncompleteGamma(float64(dof)/2, chiSquare/2)
+}
+
+// upperIncompleteGamma returns the regularized Q(a, x) = 1 - P(a, x)
+func upperIncompleteGamma(a float64, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lgammaA, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgammaA)
+
+	// Series expansion of P(a, x) converges quickly for small x
+	if x < a+1 {
+		sum := 1 / a
+		term := sum
+		for n := 1; n < 200; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-12 {
+				break
+			}
+		}
+		return 1 - prefix*sum
+	}
+
+	// Continued fraction of Q(a, x) for large x (modified Lentz)
+	tiny := 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < 200; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-12 {
+			break
+		}
+	}
+	return prefix * h
+}
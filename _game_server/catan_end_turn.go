@@ -1,124 +1,31 @@
 package _game_server
 
-import "fmt"
-
-func (g *GameSavestate) EndTurn(player Player) {
-	CheckLongestRoad(g.Players, g.LongestRoadOwnerID)
-	CheckLargestArmy(g.Players, g.CurrentLargestArmy)
-
-	g.UpdateExtraPoints(g.Players)
-
-	fmt.Sprintf("End of turn of Player: %i . Next player is: %i", player.ID, ((player.ID + 1) % (len(g.Players))))
-}
-
-// ----------------------------------------------------------------------------------------------------------
-
-func (g *GameSavestate) UpdateExtraPoints(players map[int]*Player) {
-	// Minimum requirement is 5 segments
-	thresholdRoad := 5
-	// Minimum requirement is 3 Knights
-	thresholdKnights := 3
-
-	if g.LongestRoadOwnerID != -1 {
-		// To take the trophy, you must strictly BEAT the current owner
-		thresholdRoad = g.CurrentLongestRoad
-	}
-	if g.CurrentLargestArmy != -1 {
-		// To take the trophy, you must strictly BEAT the current owner
-		thresholdKnights = g.CurrentLargestArmy
+func (g *GameSavestate) EndTurn(playerID int) error {
+	if err := g.checkAction(ActionEndTurn, playerID); err != nil {
+		return err
 	}
+	g.Phase = PhaseEndTurn
 
-	for _, p := range players {
-		if p.LongestRoad > thresholdRoad {
-			// Remove 2 points from old owner
-			if oldOwner, ok := players[g.LongestRoadOwnerID]; ok {
-				oldOwner.Points -= 2
-			}
-
-			// Assign to new owner
-			g.LongestRoadOwnerID = p.ID
-			g.CurrentLongestRoad = p.LongestRoad
-			p.Points += 2
-		}
-		if p.KnightsPlayed > thresholdKnights {
-			// Remove 2 points from old owner
-			if oldOwner, ok := players[g.LargestArmyOwnerID]; ok {
-				oldOwner.Points -= 2
-			}
-
-			// Assign to new owner
-			g.LargestArmyOwnerID = p.ID
-			g.CurrentLargestArmy = p.KnightsPlayed
-			p.Points += 2
-		}
+	g.afterAction()
+	if g.Phase == PhaseGameOver {
+		return nil
 	}
-}
 
-func (g *GameSavestate) UpdateLongestRoadTrophy() {
-	oldOwnerID := g.LongestRoadOwnerID
-	bestID := -1
-	bestLen := 4 // Must be at least 5 to qualify
-
-	// Find who currently has the longest road on the board
-	for _, p := range g.Players {
-		if p.LongestRoad > bestLen {
-			bestLen = p.LongestRoad
-			bestID = p.ID
-		}
+	// Hand over to the next player, a new round starts with the first seat
+	g.Players[playerID].PlayedCardThisTurn = false
+	g.TradeOffers = nil
+	g.Turn++
+	if g.nextPlayer() {
+		g.Round++
 	}
+	g.Phase = PhaseRoll
 
-	// Case A: The trophy moves to a new person (must STRICTLY beat old owner)
-	if oldOwnerID != -1 && bestID != oldOwnerID {
-		currentOwner := g.Players[oldOwnerID]
-		// If the old owner's road was broken and someone else is now longer
-		if bestLen > currentOwner.LongestRoad {
-			currentOwner.Points -= 2
-			g.Players[bestID].Points += 2
-			g.LongestRoadOwnerID = bestID
-			g.MaxRoadLength = bestLen
-		}
-	} else if oldOwnerID == -1 && bestID != -1 { // Case B: First time someone hits 5 segments
-		g.Players[bestID].Points += 2
-		g.LongestRoadOwnerID = bestID
-		g.MaxRoadLength = bestLen
-	}
+	g.emit(EventTurnEnded, playerID, TurnEnded{Turn: g.Turn - 1, Round: g.Round, NextPlayerID: g.CurrentPlayerID})
+	return nil
 }
 
-// -------------------------------------------------------------------------------------------------------
-
-func CheckLargestArmy(players map[int]*Player, currentOwnerID int) int {
-	bestID := currentOwnerID
-	maxKnights := 2 // Minimum requirement is 3 knights
-
-	if currentOwnerID != -1 {
-		maxKnights = players[currentOwnerID].KnightsPlayed
-	}
-
-	for id, p := range players {
-		if p.KnightsPlayed > maxKnights {
-			maxKnights = p.KnightsPlayed
-			bestID = id
-		}
-	}
-	return bestID
-}
-
-func CheckLongestRoad(players map[int]*Player, currentOwnerID int) int {
-	bestID := currentOwnerID
-	longRoad := 4 // Minimum requirement is 5 Roads conneceted
-
-	if currentOwnerID != -1 {
-		longRoad = players[currentOwnerID].LongestRoad
-	}
-
-	for id, p := range players {
-		if p.LongestRoad > longRoad {
-			longRoad = p.LongestRoad
-			bestID = id
-		}
-	}
-	return bestID
-}
+// ----------------------------------------------------------------------------------------------------------
+// Road length (the trophy itself is handled in catan_achievements.go)
 
 func (p *Player) GetLongestRoad(b *Board) int {
 	maxLen := 0
@@ -159,16 +66,13 @@ func dfsRoad(currentEdgeID int, targetCornerID int, b *Board, playerID int, visi
 
 	bestSubPath := 0
 	// Find neighboring edges
-	for _, nextEdge := range b.Edges {
+	for _, nextEdgeID := range corner.AdjacentEdges {
+		nextEdge := b.Edges[nextEdgeID]
 		if !visited[nextEdge.ID] && nextEdge.OwnerID == playerID {
-			// Check if this edge touches our target corner
-			var nextTarget int
-			if nextEdge.Corners[0] == targetCornerID {
+			// Continue on the far end of the next edge
+			nextTarget := nextEdge.Corners[0]
+			if nextTarget == targetCornerID {
 				nextTarget = nextEdge.Corners[1]
-			} else if nextEdge.Corners[1] == targetCornerID {
-				nextTarget = nextEdge.Corners[0]
-			} else {
-				continue
 			}
 
 			// Mark visited and recurse
@@ -0,0 +1,130 @@
+package _game_server
+
+//------------------------------------------------------------------------------------------------------
+// Game events
+/*
+	Things that happened in the game, numbered in order (Seq starts at 1).
+	Data is public. Private is only shown to the players in Audience (e.g.
+	the resource stolen by the Robber is seen by the thief and the victim),
+	everybody else gets the event without it, see GameEvent.ViewFor.
+*/
+
+type EventType string
+
+const (
+	EventDiceRolled        EventType = "dice_rolled"
+	EventResourcesProduced EventType = "resources_produced"
+	EventDiscarded         EventType = "discarded"
+	EventPieceBuilt        EventType = "piece_built"
+	EventRobberMoved       EventType = "robber_moved"
+	EventMaritimeTraded    EventType = "maritime_traded"
+	EventTradeProposed     EventType = "trade_proposed"
+	EventTradeAccepted     EventType = "trade_accepted"
+	EventCardBought        EventType = "card_bought"
+	EventCardPlayed        EventType = "card_played"
+	EventTrophyChanged     EventType = "trophy_changed"
+	EventTurnEnded         EventType = "turn_ended"
+	EventGameWon           EventType = "game_won"
+	EventCommandUndone     EventType = "command_undone"
+)
+
+type GameEvent struct {
+	Seq      int
+	Type     EventType
+	PlayerID int // Player the event is about, -1 if none
+	Data     interface{}
+	Private  interface{} `json:",omitempty"` // Only for the players in Audience
+
+	Audience []int `json:"-"`
+}
+
+// Data of the events
+
+type PieceBuilt struct {
+	Piece    PieceType
+	Location int  // Corner ID, edge ID for roads
+	Free     bool // Initial placement and Road Building
+}
+
+type RobberMoved struct {
+	HexID    int
+	VictimID int // -1 if nobody was robbed
+}
+
+type Discarded struct {
+	Count int // Private: the ResourceMap
+}
+
+type MaritimeTraded struct {
+	Give  Resource
+	Take  Resource
+	Ratio int
+}
+
+type CardPlayed struct {
+	Card      ActionCard
+	Resources []Resource `json:",omitempty"` // Year of Plenty picks, Monopoly target
+	Amount    int        `json:",omitempty"` // Cards taken with Monopoly
+}
+
+type TradeProposal struct {
+	OfferID int
+	TradeOffer
+}
+
+type TurnEnded struct {
+	Turn         int // Turn that ended
+	Round        int // Round of the next turn
+	NextPlayerID int
+}
+
+type CommandUndone struct {
+	Seq  int // Command that was taken back (see Command.Seq)
+	Type CommandType
+}
+
+func (g *GameSavestate) emit(eventType EventType, playerID int, data interface{}) {
+	g.emitPrivate(eventType, playerID, data, nil)
+}
+
+// emitPrivate records an event with a part that only the audience may see
+func (g *GameSavestate) emitPrivate(eventType EventType, playerID int, data interface{}, private interface{}, audience ...int) {
+	g.Events = append(g.Events, GameEvent{
+		Seq:      len(g.Events) + 1,
+		Type:     eventType,
+		PlayerID: playerID,
+		Data:     data,
+		Private:  private,
+		Audience: audience,
+	})
+}
+
+// ViewFor returns the event as viewerID may see it
+func (e GameEvent) ViewFor(viewerID int) GameEvent {
+	visible := false
+	for _, id := range e.Audience {
+		if id == viewerID {
+			visible = true
+		}
+	}
+	if !visible {
+		e.Private = nil
+	}
+	e.Audience = nil
+	return e
+}
+
+// EventsSince returns the events after seq as viewerID sees them
+func (g *GameSavestate) EventsSince(seq int, viewerID int) []GameEvent {
+	if seq < 0 {
+		seq = 0
+	}
+	if seq >= len(g.Events) {
+		return nil
+	}
+	events := make([]GameEvent, 0, len(g.Events)-seq)
+	for _, event := range g.Events[seq:] {
+		events = append(events, event.ViewFor(viewerID))
+	}
+	return events
+}
@@ -0,0 +1,360 @@
+package _game_server
+
+import "fmt"
+
+//------------------------------------------------------------------------------------------------------
+// Game level actions
+// Every action first checks phase and turn, then runs the rule functions on the game state
+
+// Roll rolls the dice for the active player, a 7 produces nothing and starts the Robber
+func (g *GameSavestate) Roll(playerID int) (DiceRoll, ProductionReport, error) {
+	if err := g.checkAction(ActionRoll, playerID); err != nil {
+		return DiceRoll{}, ProductionReport{}, err
+	}
+
+	roll := g.RollDice()
+	g.emit(EventDiceRolled, playerID, roll)
+	if roll.Total == 7 {
+		g.startRobber()
+		return roll, ProductionReport{Roll: roll.Total}, nil
+	}
+
+	report := g.Board.DistributeResources(roll.Total, g.Players, g.Bank)
+	g.emit(EventResourcesProduced, playerID, report)
+	g.Phase = PhaseTradeBuild
+	return roll, report, nil
+}
+
+//------------------------------------------------------------------------------------------------------
+// Rolling a 7: every player with more than 7 cards discards half, then the
+// active player moves the Robber and steals from a player on the new hex
+
+func (g *GameSavestate) startRobber() {
+	g.Phase = PhaseRobber
+	g.PendingDiscards = make(map[int]int)
+	for id, p := range g.Players {
+		if total := p.TotalResources(); total > 7 {
+			g.PendingDiscards[id] = total / 2
+		}
+	}
+}
+
+// Discard is submitted by every player listed in PendingDiscards, in any order
+func (g *GameSavestate) Discard(playerID int, discard ResourceMap) error {
+	if err := g.checkAction(ActionDiscard, playerID); err != nil {
+		return err
+	}
+	pending, ok := g.PendingDiscards[playerID]
+	if !ok {
+		return fmt.Errorf("player %d does not need to discard", playerID)
+	}
+	count := 0
+	for _, amount := range discard {
+		count += amount
+	}
+	if count != pending {
+		return fmt.Errorf("must discard exactly %d cards", pending)
+	}
+	if err := g.Players[playerID].HandleSevenRoll(discard, g.Bank); err != nil {
+		return err
+	}
+
+	g.emitPrivate(EventDiscarded, playerID, Discarded{Count: pending}, discard, playerID)
+	delete(g.PendingDiscards, playerID)
+	return nil
+}
+
+// MoveRobber places the Robber after a 7 was rolled and robs victimID (-1 if nobody can be robbed)
+func (g *GameSavestate) MoveRobber(playerID int, hexID int, victimID int) (Resource, error) {
+	if err := g.checkAction(ActionMoveRobber, playerID); err != nil {
+		return None, err
+	}
+	if len(g.PendingDiscards) > 0 {
+		return None, fmt.Errorf("waiting for %d player(s) to discard", len(g.PendingDiscards))
+	}
+	if _, ok := g.Board.Hexes[hexID]; !ok {
+		return None, fmt.Errorf("invalid hex ID")
+	}
+
+	// 1. The chosen victim must be allowed to be robbed on the new hex
+	if err := g.checkVictim(playerID, hexID, victimID); err != nil {
+		return None, err
+	}
+
+	// 2. Move the Robber and steal
+	if err := g.Board.MoveRobber(hexID); err != nil {
+		return None, err
+	}
+	stolen := None
+	if victimID != -1 {
+		stolen = g.Players[playerID].StealResource(g.Players[victimID], g.rng)
+	}
+	g.emitRobberMoved(playerID, hexID, victimID, stolen)
+
+	g.Phase = PhaseTradeBuild
+	return stolen, nil
+}
+
+// emitRobberMoved publishes the new Robber hex, only thief and victim learn what was stolen
+func (g *GameSavestate) emitRobberMoved(playerID int, hexID int, victimID int, stolen Resource) {
+	moved := RobberMoved{HexID: hexID, VictimID: victimID}
+	if victimID == -1 {
+		g.emit(EventRobberMoved, playerID, moved)
+		return
+	}
+	g.emitPrivate(EventRobberMoved, playerID, moved, stolen, playerID, victimID)
+}
+
+// ValidVictims returns who the player may rob when moving the Robber to the hex.
+// Players with cards come first, an empty handed player only qualifies if
+// nobody else with a building on the hex has cards. Empty if nobody can be robbed.
+func (g *GameSavestate) ValidVictims(playerID int, hexID int) []int {
+	candidates := g.Board.RobberVictims(hexID, playerID)
+
+	withCards := []int{}
+	for _, id := range candidates {
+		if g.Players[id].TotalResources() > 0 {
+			withCards = append(withCards, id)
+		}
+	}
+	if len(withCards) > 0 {
+		return withCards
+	}
+	return candidates
+}
+
+// checkVictim validates the victim chosen for the Robber (-1 if nobody can be robbed)
+func (g *GameSavestate) checkVictim(playerID int, hexID int, victimID int) error {
+	victims := g.ValidVictims(playerID, hexID)
+	if len(victims) == 0 && victimID == -1 {
+		return nil
+	}
+	for _, id := range victims {
+		if id == victimID {
+			return nil
+		}
+	}
+	return fmt.Errorf("player %d cannot be robbed on hex %d (valid victims: %v)", victimID, hexID, victims)
+}
+
+func (g *GameSavestate) BuildRoad(playerID int, edgeID int) error {
+	if err := g.checkAction(ActionBuildRoad, playerID); err != nil {
+		return err
+	}
+	if g.Phase == PhaseInitialPlacement {
+		return g.placeInitialRoad(g.Players[playerID], edgeID)
+	}
+	if err := g.Players[playerID].BuildRoad(edgeID, g.Board, g.Bank, false); err != nil {
+		return err
+	}
+	g.emit(EventPieceBuilt, playerID, PieceBuilt{Piece: PieceRoad, Location: edgeID})
+
+	g.afterAction()
+	return nil
+}
+
+func (g *GameSavestate) MaritimeTrade(playerID int, give Resource, take Resource, ratio int) error {
+	if err := g.checkAction(ActionMaritimeTrade, playerID); err != nil {
+		return err
+	}
+	if ratio == 0 {
+		ratio = g.Board.TradeRatio(playerID, give)
+	}
+	if err := g.Players[playerID].MaritimeTrade(give, take, ratio, g.Board, g.Bank); err != nil {
+		return err
+	}
+
+	g.emit(EventMaritimeTraded, playerID, MaritimeTraded{Give: give, Take: take, Ratio: ratio})
+	return nil
+}
+
+// ProposeTrade offers a trade to another player under offerID (the Seq of the
+// proposing command). Offers stay open until they are accepted or the turn ends.
+func (g *GameSavestate) ProposeTrade(offerID int, offer TradeOffer) error {
+	if err := g.checkAction(ActionPlayerTrade, offer.SenderID); err != nil {
+		return err
+	}
+	if _, ok := g.Players[offer.ReceiverID]; !ok || offer.ReceiverID == offer.SenderID {
+		return fmt.Errorf("invalid trade partner")
+	}
+	if err := checkTradeSide(offer.Give); err != nil {
+		return fmt.Errorf("invalid offer: %w", err)
+	}
+	if err := checkTradeSide(offer.Receive); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	if !g.Players[offer.SenderID].CanAfford(offer.Give) {
+		return fmt.Errorf("sender cannot afford the trade")
+	}
+	if _, exists := g.TradeOffers[offerID]; exists {
+		return fmt.Errorf("trade offer %d already exists", offerID)
+	}
+
+	if g.TradeOffers == nil {
+		g.TradeOffers = make(map[int]TradeOffer)
+	}
+	g.TradeOffers[offerID] = offer
+	g.emit(EventTradeProposed, offer.SenderID, TradeProposal{OfferID: offerID, TradeOffer: offer})
+	return nil
+}
+
+// checkTradeSide accepts one side of a trade offer if it holds at least one card
+// and only positive amounts of real resources
+func checkTradeSide(cards ResourceMap) error {
+	if len(cards) == 0 {
+		return fmt.Errorf("no resources")
+	}
+	for res, amount := range cards {
+		if !res.Valid() || amount <= 0 {
+			return fmt.Errorf("%d x resource %d", amount, res)
+		}
+	}
+	return nil
+}
+
+// AcceptTrade is called by the receiver of an open offer and executes the trade
+func (g *GameSavestate) AcceptTrade(playerID int, offerID int) error {
+	if err := g.checkAction(ActionAcceptTrade, playerID); err != nil {
+		return err
+	}
+	offer, ok := g.TradeOffers[offerID]
+	if !ok {
+		return fmt.Errorf("no open trade offer %d", offerID)
+	}
+	if offer.ReceiverID != playerID {
+		return fmt.Errorf("trade offer %d is not addressed to player %d", offerID, playerID)
+	}
+	if err := ExecutePlayerTrade(g.Players[offer.SenderID], g.Players[playerID], offer); err != nil {
+		return err
+	}
+
+	delete(g.TradeOffers, offerID)
+	g.emit(EventTradeAccepted, playerID, TradeProposal{OfferID: offerID, TradeOffer: offer})
+	return nil
+}
+
+func (g *GameSavestate) BuyActionCard(playerID int) (ActionCard, error) {
+	if err := g.checkAction(ActionBuyActionCard, playerID); err != nil {
+		return -1, err
+	}
+	card, err := g.Players[playerID].DrawActionCard(g.Bank, g.Turn)
+	if err != nil {
+		return -1, err
+	}
+	g.emitPrivate(EventCardBought, playerID, nil, card, playerID)
+
+	// A victory point card counts right away
+	g.afterAction()
+	return card, nil
+}
+
+//------------------------------------------------------------------------------------------------------
+// Playing action cards: check the card is playable, run the effect, then remove the card
+
+func (g *GameSavestate) PlayKnight(playerID int, targetHexID int, victimID int) (Resource, error) {
+	if err := g.checkAction(ActionPlayActionCard, playerID); err != nil {
+		return None, err
+	}
+	player := g.Players[playerID]
+	index, err := player.playableCard(Knight, g.Turn)
+	if err != nil {
+		return None, err
+	}
+	if _, ok := g.Board.Hexes[targetHexID]; !ok {
+		return None, fmt.Errorf("invalid hex ID")
+	}
+	if err := g.checkVictim(playerID, targetHexID, victimID); err != nil {
+		return None, err
+	}
+
+	stolen, err := player.PlayKnight(g.Board, targetHexID, g.Players[victimID], g.rng)
+	if err != nil {
+		return None, err
+	}
+	player.discardPlayedCard(index)
+	g.emit(EventCardPlayed, playerID, CardPlayed{Card: Knight})
+	g.emitRobberMoved(playerID, targetHexID, victimID, stolen)
+	g.afterAction()
+	return stolen, nil
+}
+
+func (g *GameSavestate) PlayYearOfPlenty(playerID int, res1 Resource, res2 Resource) error {
+	if err := g.checkAction(ActionPlayActionCard, playerID); err != nil {
+		return err
+	}
+	player := g.Players[playerID]
+	index, err := player.playableCard(YearOfPlenty, g.Turn)
+	if err != nil {
+		return err
+	}
+
+	if err := player.PlayYearOfPlenty(res1, res2, g.Bank); err != nil {
+		return err
+	}
+	player.discardPlayedCard(index)
+	g.emit(EventCardPlayed, playerID, CardPlayed{Card: YearOfPlenty, Resources: []Resource{res1, res2}})
+	return nil
+}
+
+func (g *GameSavestate) PlayMonopoly(playerID int, target Resource) (int, error) {
+	if err := g.checkAction(ActionPlayActionCard, playerID); err != nil {
+		return 0, err
+	}
+	player := g.Players[playerID]
+	index, err := player.playableCard(Monopoly, g.Turn)
+	if err != nil {
+		return 0, err
+	}
+	if !target.Valid() {
+		return 0, fmt.Errorf("invalid resource")
+	}
+
+	stolen := player.PlayMonopoly(target, g.Players)
+	player.discardPlayedCard(index)
+	g.emit(EventCardPlayed, playerID, CardPlayed{Card: Monopoly, Resources: []Resource{target}, Amount: stolen})
+	return stolen, nil
+}
+
+// PlayRoadBuilding places one or two free roads, as many as the supply has left.
+// If the second road is invalid the first is taken back.
+func (g *GameSavestate) PlayRoadBuilding(playerID int, edgeIDs []int) error {
+	if err := g.checkAction(ActionPlayActionCard, playerID); err != nil {
+		return err
+	}
+	player := g.Players[playerID]
+	index, err := player.playableCard(RoadBuilding, g.Turn)
+	if err != nil {
+		return err
+	}
+	if len(edgeIDs) < 1 || len(edgeIDs) > 2 {
+		return fmt.Errorf("road building places one or two roads")
+	}
+	// With a single road left in the supply only the first edge is built
+	remaining := player.Pieces.Remaining(PieceRoad)
+	if remaining == 0 {
+		return &SupplyError{Piece: PieceRoad}
+	}
+	if len(edgeIDs) > remaining {
+		edgeIDs = edgeIDs[:remaining]
+	}
+
+	// Each road is checked for connectivity on its own, the second may extend the first
+	placed := []int{}
+	for _, edgeID := range edgeIDs {
+		if err := player.BuildRoad(edgeID, g.Board, g.Bank, true); err != nil {
+			for _, undoID := range placed {
+				player.removeRoad(undoID, g.Board)
+			}
+			return err
+		}
+		placed = append(placed, edgeID)
+	}
+
+	player.discardPlayedCard(index)
+	g.emit(EventCardPlayed, playerID, CardPlayed{Card: RoadBuilding})
+	for _, edgeID := range placed {
+		g.emit(EventPieceBuilt, playerID, PieceBuilt{Piece: PieceRoad, Location: edgeID, Free: true})
+	}
+	g.afterAction()
+	return nil
+}
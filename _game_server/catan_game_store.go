@@ -0,0 +1,193 @@
+package _game_server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Game store
+/*
+	Keeps the command log of every started game, so the games can be
+	replayed after a restart or crash (see Lobby.Restore). A log starts with
+	the header of the game, then one record per accepted command.
+
+	FileStore writes one JSON line per record to <dir>/game-<id>.log and
+	syncs it before the command is confirmed. A line cut off by a crash was
+	never confirmed, it is cut from the file when the log is loaded.
+*/
+
+// Environment variable with the directory of the FileStore, games are kept in memory only if it is not set
+const DataDirEnv = "CATAN_DATA_DIR"
+
+// GameHeader is everything besides the commands that is needed to replay a game
+type GameHeader struct {
+	ID    int
+	Seed  int64
+	Salt  int64
+	Rules Rules
+	Seats []Seat
+	Key   string // Table key of the seat tokens, tokens stay valid if the server secret is kept
+}
+
+// StoredGame is a game as loaded from the store
+type StoredGame struct {
+	Header    GameHeader
+	Commands  []Command
+	Abandoned bool
+}
+
+type GameStore interface {
+	Create(header GameHeader) error
+	Append(gameID int, cmd Command) error
+	Abandon(gameID int) error
+	Delete(gameID int) error
+	Load() ([]StoredGame, error)
+}
+
+// logRecord is one line of a log file, exactly one field is set
+type logRecord struct {
+	Header    *GameHeader `json:",omitempty"`
+	Command   *Command    `json:",omitempty"`
+	Abandoned bool        `json:",omitempty"`
+}
+
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(gameID int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("game-%d.log", gameID))
+}
+
+func (s *FileStore) Create(header GameHeader) error {
+	file, err := os.OpenFile(s.path(header.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	return writeRecord(file, logRecord{Header: &header})
+}
+
+func (s *FileStore) Append(gameID int, cmd Command) error {
+	file, err := os.OpenFile(s.path(gameID), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	return writeRecord(file, logRecord{Command: &cmd})
+}
+
+func (s *FileStore) Abandon(gameID int) error {
+	file, err := os.OpenFile(s.path(gameID), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	return writeRecord(file, logRecord{Abandoned: true})
+}
+
+func (s *FileStore) Delete(gameID int) error {
+	err := os.Remove(s.path(gameID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // The game was never started
+	}
+	return err
+}
+
+// writeRecord appends one line, syncs and closes the file
+func writeRecord(file *os.File, record logRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Load reads every log in the directory, ordered by game ID
+func (s *FileStore) Load() ([]StoredGame, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	games := []StoredGame{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "game-") || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		game, err := loadLog(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		games = append(games, game)
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].Header.ID < games[j].Header.ID })
+	return games, nil
+}
+
+func loadLog(path string) (StoredGame, error) {
+	var game StoredGame
+	file, err := os.Open(path)
+	if err != nil {
+		return game, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	complete := int64(0) // Length of the complete lines
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A last line without newline was cut off by a crash, later records must not be appended to it
+			if len(data) > 0 {
+				if err := os.Truncate(path, complete); err != nil {
+					return game, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return game, err
+		}
+		complete += int64(len(data))
+
+		var record logRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return game, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch {
+		case record.Header != nil:
+			game.Header = *record.Header
+		case record.Command != nil:
+			game.Commands = append(game.Commands, *record.Command)
+		case record.Abandoned:
+			game.Abandoned = true
+		}
+	}
+	if game.Header.Seats == nil {
+		return game, errors.New("missing header")
+	}
+	return game, nil
+}
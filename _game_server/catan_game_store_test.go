@@ -0,0 +1,124 @@
+package _game_server
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// restoredGame restores a lobby from the store and returns the game of the table
+func restoredGame(t *testing.T, signer *TokenSigner, store GameStore, id int) (*Table, *GameSavestate) {
+	t.Helper()
+	lobby := NewLobby(signer, store)
+	if err := lobby.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	table, err := lobby.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table, table.game
+}
+
+func sameGame(t *testing.T, want *GameSavestate, got *GameSavestate) {
+	t.Helper()
+	if fingerprint(t, got) != fingerprint(t, want) {
+		t.Error("the restored state differs")
+	}
+	wantEvents, _ := json.Marshal(want.Events)
+	gotEvents, _ := json.Marshal(got.Events)
+	if string(gotEvents) != string(wantEvents) {
+		t.Error("the restored events differ")
+	}
+	if len(got.Commands) != len(want.Commands) {
+		t.Errorf("restored %d of %d commands", len(got.Commands), len(want.Commands))
+	}
+}
+
+func TestFileStoreRestore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := NewTokenSigner([]byte("test"))
+	lobby := NewLobby(signer, store)
+
+	// 1. Play a game with undos, every command is saved through the table
+	table, err := lobby.Create(TableConfig{Seed: 11, Seats: 3, Rules: StandardRules()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := make([]string, 3)
+	for seat := range tokens {
+		if tokens[seat], err = table.Join(seat, "player"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := table.Start(); err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(11))
+	for i := 0; i < 1000 && table.Status() == TablePlaying; i++ {
+		table.Command(func(g *GameSavestate) (interface{}, error) {
+			return g.Execute(botCommand(g, r))
+		})
+	}
+
+	undos := 0
+	for _, cmd := range table.game.Commands {
+		if cmd.Type == CommandUndo {
+			undos++
+		}
+	}
+	if undos == 0 {
+		t.Fatal("the game has no undo to restore")
+	}
+
+	// 2. A new lobby on the same directory replays the same game, seat tokens stay valid
+	restored, game := restoredGame(t, signer, store, table.id)
+	sameGame(t, table.game, game)
+	if game.Salt != table.game.Salt {
+		t.Error("the salt was not restored")
+	}
+	if playerID, err := restored.Authenticate(tokens[2]); err != nil || playerID != 2 {
+		t.Errorf("token of seat 2: player %d, %v", playerID, err)
+	}
+
+	// 3. A record cut off by a crash is dropped, later commands are appended after the last complete one
+	file, err := os.OpenFile(store.path(table.id), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"Command":{"Seq":`)
+	file.Close()
+
+	restored, game = restoredGame(t, signer, store, table.id)
+	sameGame(t, table.game, game)
+	for logged := len(game.Commands); restored.Status() == TablePlaying && len(restored.game.Commands) == logged; {
+		restored.Command(func(g *GameSavestate) (interface{}, error) {
+			return g.Execute(botCommand(g, r))
+		})
+	}
+	_, again := restoredGame(t, signer, store, table.id)
+	sameGame(t, restored.game, again)
+}
+
+func TestTokenSecretKept(t *testing.T) {
+	t.Setenv(TokenSecretEnv, "")
+	dir := t.TempDir()
+
+	// A restart with the same data directory accepts the tokens of the last run
+	first, err := LoadTokenSigner(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := first.Issue(1, 2, "key")
+	second, err := LoadTokenSigner(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playerID, err := second.Verify(token, 1, "key"); err != nil || playerID != 2 {
+		t.Errorf("token after restart: player %d, %v", playerID, err)
+	}
+}
@@ -0,0 +1,114 @@
+package _game_server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Harbors
+/*
+	A harbor sits on a coastal edge (an edge with only one hex on the board).
+	A player with a settlement or city on one of its two corners trades with
+	the bank at 3:1 for any resource (generic harbor) or 2:1 for the harbor's
+	resource. Without a harbor the rate is 4:1.
+*/
+
+type Harbor struct {
+	ID       int
+	Resource Resource // None for a generic 3:1 harbor
+	Ratio    int
+	EdgeID   int
+	Corners  [2]int
+}
+
+// Harbors of the base game, in clockwise order starting at the top of the board
+var standardHarbors = []Resource{None, Sheep, None, Rock, None, Wheat, None, Wood, Clay}
+
+const defaultTradeRatio = 4
+
+// CoastalEdges returns the edges on the outline of the board, clockwise starting at the top
+func (b *Board) CoastalEdges() []int {
+	// Center of the board, to order the edges by angle
+	var center Point
+	for _, hex := range b.Hexes {
+		p := hex.Coord.Pixel(1)
+		center.X += p.X / float64(len(b.Hexes))
+		center.Y += p.Y / float64(len(b.Hexes))
+	}
+
+	coast := []int{}
+	angle := make(map[int]float64)
+	for _, edge := range b.Edges {
+		onBoard := 0
+		for _, coord := range edge.Coord.Hexes() {
+			if _, ok := b.HexAt(coord); ok {
+				onBoard++
+			}
+		}
+		if onBoard != 1 {
+			continue
+		}
+
+		// Screen coordinates grow downwards, so a growing angle runs clockwise
+		mid := edge.Coord.Pixel(1)
+		a := math.Atan2(mid.Y-center.Y, mid.X-center.X) + math.Pi/2
+		if a < 0 {
+			a += 2 * math.Pi
+		}
+		coast = append(coast, edge.ID)
+		angle[edge.ID] = a
+	}
+
+	sort.Slice(coast, func(i, j int) bool {
+		return angle[coast[i]] < angle[coast[j]]
+	})
+	return coast
+}
+
+// PlaceHarbors spreads the harbors evenly along the coast, starting offset edges
+// clockwise from the top. Harbors never share a corner.
+func (b *Board) PlaceHarbors(types []Resource, offset int) error {
+	b.Harbors = make(map[int]*Harbor)
+	if len(types) == 0 {
+		return nil
+	}
+
+	coast := b.CoastalEdges()
+	if len(coast) < 2*len(types) {
+		return fmt.Errorf("coast of %d edges is too short for %d harbors", len(coast), len(types))
+	}
+
+	for id, res := range types {
+		edge := b.Edges[coast[(offset+id*len(coast)/len(types))%len(coast)]]
+		ratio := 2
+		if res == None {
+			ratio = 3
+		}
+		b.Harbors[id] = &Harbor{
+			ID:       id,
+			Resource: res,
+			Ratio:    ratio,
+			EdgeID:   edge.ID,
+			Corners:  edge.Corners,
+		}
+	}
+	return nil
+}
+
+// TradeRatio returns the best bank ratio playerID may use when giving away the resource
+func (b *Board) TradeRatio(playerID int, give Resource) int {
+	best := defaultTradeRatio
+	for _, harbor := range b.Harbors {
+		if harbor.Resource != None && harbor.Resource != give {
+			continue
+		}
+		for _, cornerID := range harbor.Corners {
+			if b.Corners[cornerID].OwnerID == playerID && harbor.Ratio < best {
+				best = harbor.Ratio
+			}
+		}
+	}
+	return best
+}
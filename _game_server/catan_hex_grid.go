@@ -0,0 +1,333 @@
+package _game_server
+
+import "math"
+
+//------------------------------------------------------------------------------------------------------
+// Hex grid geometry
+/*
+	Hexes are pointy-top and addressed with axial coordinates (Q, R).
+	The third cube coordinate is S = -Q - R. Q grows to the east, R grows to
+	the south-east, so every row of the board shares the same R.
+
+	Every hex owns two corners (North and South) and three edges (NE, NW, W).
+	All other corners/edges of a hex are owned by one of its neighbours, which
+	gives every Corner and Edge exactly one canonical coordinate:
+
+	               N
+	        NW  /     \  NE
+	           /       \
+	          |  (Q,R)  |
+	        W |         | (W of the east neighbour)
+	           \       /
+	            \     /
+	               S
+*/
+
+type HexCoord struct {
+	Q int
+	R int
+}
+
+// Directions in clockwise order, starting East
+const (
+	East = iota
+	SouthEast
+	SouthWest
+	West
+	NorthWest
+	NorthEast
+)
+
+var hexDirections = [6]HexCoord{
+	East:      {1, 0},
+	SouthEast: {0, 1},
+	SouthWest: {-1, 1},
+	West:      {-1, 0},
+	NorthWest: {0, -1},
+	NorthEast: {1, -1},
+}
+
+func (h HexCoord) S() int {
+	return -h.Q - h.R
+}
+
+func (h HexCoord) Add(other HexCoord) HexCoord {
+	return HexCoord{Q: h.Q + other.Q, R: h.R + other.R}
+}
+
+func (h HexCoord) Scale(k int) HexCoord {
+	return HexCoord{Q: h.Q * k, R: h.R * k}
+}
+
+func (h HexCoord) Neighbor(direction int) HexCoord {
+	return h.Add(hexDirections[direction])
+}
+
+// Neighbors returns the six surrounding hexes, clockwise starting East
+func (h HexCoord) Neighbors() [6]HexCoord {
+	var list [6]HexCoord
+	for dir := range hexDirections {
+		list[dir] = h.Neighbor(dir)
+	}
+	return list
+}
+
+// Distance is the number of hex steps between two hexes
+func (h HexCoord) Distance(other HexCoord) int {
+	dq := abs(h.Q - other.Q)
+	dr := abs(h.R - other.R)
+	ds := abs(h.S() - other.S())
+	return (dq + dr + ds) / 2
+}
+
+// HexRing returns all hexes at exactly radius steps from center, clockwise
+// starting with the corner hex in direction startDir
+func HexRing(center HexCoord, radius int, startDir int) []HexCoord {
+	if radius == 0 {
+		return []HexCoord{center}
+	}
+	ring := []HexCoord{}
+	hex := center.Add(hexDirections[startDir].Scale(radius))
+	// Walking clockwise along a side means heading two directions further
+	for side := 0; side < 6; side++ {
+		dir := (startDir + side + 2) % 6
+		for step := 0; step < radius; step++ {
+			ring = append(ring, hex)
+			hex = hex.Neighbor(dir)
+		}
+	}
+	return ring
+}
+
+// HexSpiral returns all hexes up to radius steps from center, from the
+// outermost ring inwards, each ring starting in direction startDir
+func HexSpiral(center HexCoord, radius int, startDir int) []HexCoord {
+	spiral := []HexCoord{}
+	for k := radius; k >= 0; k-- {
+		spiral = append(spiral, HexRing(center, k, startDir)...)
+	}
+	return spiral
+}
+
+//------------------------------------------------------------------------------------------------------
+// Board shapes (the order of the slice defines the Hex IDs)
+
+// HexagonShape returns a hexagon shaped board in reading order
+func HexagonShape(radius int) []HexCoord {
+	shape := []HexCoord{}
+	for r := -radius; r <= radius; r++ {
+		for q := -radius; q <= radius; q++ {
+			hex := HexCoord{Q: q, R: r}
+			if hex.Distance(HexCoord{}) <= radius {
+				shape = append(shape, hex)
+			}
+		}
+	}
+	return shape
+}
+
+// RowShape returns a board of centered rows, e.g. 3-4-5-4-3 for the
+// standard board or 3-4-5-6-5-4-3 for the 5-6 player extension
+func RowShape(rows []int) []HexCoord {
+	shape := []HexCoord{}
+	for i, n := range rows {
+		r := i - len(rows)/2
+		// Center the row around x = 0 (x = 2Q + R in half hex widths)
+		firstQ := floorDiv(-(n-1)-r, 2)
+		for q := firstQ; q < firstQ+n; q++ {
+			shape = append(shape, HexCoord{Q: q, R: r})
+		}
+	}
+	return shape
+}
+
+// StandardShape is the 19 hex 3-4-5-4-3 board
+func StandardShape() []HexCoord {
+	return HexagonShape(2)
+}
+
+//------------------------------------------------------------------------------------------------------
+// Corners
+
+type CornerDir int
+
+const (
+	CornerNorth CornerDir = iota
+	CornerSouth
+)
+
+type CornerCoord struct {
+	Q   int
+	R   int
+	Dir CornerDir
+}
+
+// Corners returns the six corners of a hex, clockwise starting at the top
+func (h HexCoord) Corners() [6]CornerCoord {
+	ne := h.Neighbor(NorthEast)
+	se := h.Neighbor(SouthEast)
+	sw := h.Neighbor(SouthWest)
+	nw := h.Neighbor(NorthWest)
+	return [6]CornerCoord{
+		{h.Q, h.R, CornerNorth},
+		{ne.Q, ne.R, CornerSouth},
+		{se.Q, se.R, CornerNorth},
+		{h.Q, h.R, CornerSouth},
+		{sw.Q, sw.R, CornerNorth},
+		{nw.Q, nw.R, CornerSouth},
+	}
+}
+
+func (c CornerCoord) Hex() HexCoord {
+	return HexCoord{Q: c.Q, R: c.R}
+}
+
+// Hexes returns the three hexes touching this corner (some may be off the board)
+func (c CornerCoord) Hexes() [3]HexCoord {
+	h := c.Hex()
+	if c.Dir == CornerNorth {
+		return [3]HexCoord{h, h.Neighbor(NorthWest), h.Neighbor(NorthEast)}
+	}
+	return [3]HexCoord{h, h.Neighbor(SouthWest), h.Neighbor(SouthEast)}
+}
+
+// Neighbors returns the three corners one edge away
+func (c CornerCoord) Neighbors() [3]CornerCoord {
+	if c.Dir == CornerNorth {
+		return [3]CornerCoord{
+			{c.Q, c.R - 1, CornerSouth},
+			{c.Q + 1, c.R - 1, CornerSouth},
+			{c.Q + 1, c.R - 2, CornerSouth},
+		}
+	}
+	return [3]CornerCoord{
+		{c.Q - 1, c.R + 1, CornerNorth},
+		{c.Q, c.R + 1, CornerNorth},
+		{c.Q - 1, c.R + 2, CornerNorth},
+	}
+}
+
+//------------------------------------------------------------------------------------------------------
+// Edges
+
+type EdgeDir int
+
+const (
+	EdgeNorthEast EdgeDir = iota
+	EdgeNorthWest
+	EdgeWest
+)
+
+type EdgeCoord struct {
+	Q   int
+	R   int
+	Dir EdgeDir
+}
+
+// Edges returns the six sides of a hex, clockwise starting with the NE side
+func (h HexCoord) Edges() [6]EdgeCoord {
+	e := h.Neighbor(East)
+	se := h.Neighbor(SouthEast)
+	sw := h.Neighbor(SouthWest)
+	return [6]EdgeCoord{
+		{h.Q, h.R, EdgeNorthEast},
+		{e.Q, e.R, EdgeWest},
+		{se.Q, se.R, EdgeNorthWest},
+		{sw.Q, sw.R, EdgeNorthEast},
+		{h.Q, h.R, EdgeWest},
+		{h.Q, h.R, EdgeNorthWest},
+	}
+}
+
+func (e EdgeCoord) Hex() HexCoord {
+	return HexCoord{Q: e.Q, R: e.R}
+}
+
+// Hexes returns the two hexes sharing this edge (one may be off the board)
+func (e EdgeCoord) Hexes() [2]HexCoord {
+	h := e.Hex()
+	switch e.Dir {
+	case EdgeNorthEast:
+		return [2]HexCoord{h, h.Neighbor(NorthEast)}
+	case EdgeNorthWest:
+		return [2]HexCoord{h, h.Neighbor(NorthWest)}
+	default:
+		return [2]HexCoord{h, h.Neighbor(West)}
+	}
+}
+
+// Corners returns the two end points of this edge
+func (e EdgeCoord) Corners() [2]CornerCoord {
+	h := e.Hex()
+	switch e.Dir {
+	case EdgeNorthEast:
+		ne := h.Neighbor(NorthEast)
+		return [2]CornerCoord{{h.Q, h.R, CornerNorth}, {ne.Q, ne.R, CornerSouth}}
+	case EdgeNorthWest:
+		nw := h.Neighbor(NorthWest)
+		return [2]CornerCoord{{nw.Q, nw.R, CornerSouth}, {h.Q, h.R, CornerNorth}}
+	default:
+		nw := h.Neighbor(NorthWest)
+		sw := h.Neighbor(SouthWest)
+		return [2]CornerCoord{{nw.Q, nw.R, CornerSouth}, {sw.Q, sw.R, CornerNorth}}
+	}
+}
+
+//------------------------------------------------------------------------------------------------------
+// Pixel positions (for renderers), size is the distance from center to corner
+
+type Point struct {
+	X float64
+	Y float64
+}
+
+func (h HexCoord) Pixel(size float64) Point {
+	return Point{
+		X: size * math.Sqrt(3) * (float64(h.Q) + float64(h.R)/2),
+		Y: size * 1.5 * float64(h.R),
+	}
+}
+
+func (c CornerCoord) Pixel(size float64) Point {
+	center := c.Hex().Pixel(size)
+	if c.Dir == CornerNorth {
+		return Point{X: center.X, Y: center.Y - size}
+	}
+	return Point{X: center.X, Y: center.Y + size}
+}
+
+// Pixel returns the midpoint of the edge
+func (e EdgeCoord) Pixel(size float64) Point {
+	ends := e.Corners()
+	a, b := ends[0].Pixel(size), ends[1].Pixel(size)
+	return Point{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+}
+
+// lattice returns an exact integer position (half hex widths, quarter hex heights)
+// used to order corners top to bottom, left to right
+func (c CornerCoord) lattice() (int, int) {
+	x := 2*c.Q + c.R
+	y := 3 * c.R
+	if c.Dir == CornerSouth {
+		y += 4
+	}
+	return x, y
+}
+
+//------------------------------------------------------------------------------------------------------
+// helpers
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func floorDiv(a int, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
@@ -1,79 +1,68 @@
 package _game_server
 
-import "math/rand"
-import "time"
-
-// TODO is it necessary ?
-/*func initGame(gameID int, playerID []int) GameSavestate {
-
-	gamestate := GameSavestate{
-		ID:          gameID,
-		Round:       1,
-		Board:       NewStandardBoard(),
-		Players:     player,
-		Bank:        bank,
-		ActionCards: generateActionCardDeck(),
+// NewGame sets up a game whose board derives from seed, deck and dice from seed and salt
+func NewGame(gameID int, seed int64, salt int64, playerIDs []int, rules Rules) (*GameSavestate, error) {
+	board, err := GenerateBoard(DefaultBoardOptions(seed))
+	if err != nil {
+		return nil, err
 	}
 
-	return gamestate
-}*/
-
-func NewStandardBoard() *Board {
-	board := &Board{
-		Hexes:   make(map[int]*Hex),
-		Corners: make(map[int]*Corner),
-		Edges:   make(map[int]*Edge),
+	rng := NewRandomizer(seed ^ salt)
+	bank := NewBank(rng)
+
+	gamestate := &GameSavestate{
+		ID:                gameID,
+		Seed:              seed,
+		Salt:              salt,
+		Board:             board,
+		Rules:             rules,
+		Players:           NewPlayers(playerIDs, rules.PieceLimits),
+		Bank:              bank,
+		LongestRoadTrophy: newTrophy(),
+		LargestArmyTrophy: newTrophy(),
+		rng:               rng,
 	}
+	gamestate.setupTurnOrder()
 
-	// Standard Catan Resource Distribution
-	resources := []Resource{
-		None,
-		Wood, Wood, Wood, Wood,
-		Wheat, Wheat, Wheat, Wheat,
-		Sheep, Sheep, Sheep, Sheep,
-		Rock, Rock, Rock,
-		Clay, Clay, Clay,
-	}
+	return gamestate, nil
+}
 
-	// Standard Catan Numbers (excluding 7 for Desert)
-	numbers := []int{2, 3, 3, 4, 4, 5, 5, 6, 6, 8, 8, 9, 9, 10, 10, 11, 11, 12}
+// SetRandomizer replaces the random source of the game (e.g. with a ScriptedRandomizer)
+func (g *GameSavestate) SetRandomizer(r Randomizer) {
+	g.rng = r
+}
+
+func NewStandardBoard() *Board {
+	board := NewBoard(StandardShape())
 
-	// Initialize 19 Hexes
-	// In a real app, you'd shuffle these slices first
+	// Fixed layout, use GenerateBoard for shuffled (seeded) boards
 	numIdx := 0
-	for i := 0; i < 19; i++ {
+	for i := 0; i < len(standardResources); i++ {
 		val := 0
 		isRobber := false
-		if resources[i] == None {
+		if standardResources[i] == None {
 			isRobber = true
 		} else {
-			val = numbers[numIdx]
+			val = standardNumbers[numIdx]
 			numIdx++
 		}
 
-		board.Hexes[i] = &Hex{
-			ID:           i,
-			ResourceType: resources[i],
-			Value:        val,
-			HasRobber:    isRobber,
-		}
+		hex := board.Hexes[i]
+		hex.ResourceType = standardResources[i]
+		hex.Value = val
+		hex.HasRobber = isRobber
 	}
-	return board
-}
-
-// Mapping logic: Corner ID -> List of Hex IDs
-func (b *Board) SetupAdjacency() {
-	// Example: Corner 0 is at the top of the board, touching only Hex 0
-	b.Corners[0] = &Corner{ID: 0, OwnerID: -1, AdjacentHexes: []int{0}}
-
-	// Example: Corner 10 is in the middle, touching Hexes 0, 1, and 4
-	b.Corners[10] = &Corner{ID: 10, OwnerID: -1, AdjacentHexes: []int{0, 1, 4}}
 
-	// In a full implementation, you would loop through 54 corners
-	// and assign their 1-3 neighboring Hex IDs.
+	if err := board.PlaceHarbors(standardHarbors, 0); err != nil {
+		panic(err)
+	}
+	if err := board.CheckTopology(); err != nil {
+		panic(err)
+	}
+	return board
 }
 
-func NewBank() *Bank {
+func NewBank(r Randomizer) *Bank {
 
 	// Initialize Bank
 	bank := &Bank{
@@ -85,12 +74,12 @@ func NewBank() *Bank {
 			Clay:  19,
 		},
 		// Initialize and shuffle 25 ActionCards (14 Knights, 5 VPs, etc.)
-		ActionCards: generateActionCardDeck(),
+		ActionCards: generateActionCardDeck(r),
 	}
 	return bank
 }
 
-func NewPlayers(playerIDs []int) map[int]*Player {
+func NewPlayers(playerIDs []int, pieces PieceSupply) map[int]*Player {
 
 	// Initialize Players
 	players := make(map[int]*Player)
@@ -98,6 +87,7 @@ func NewPlayers(playerIDs []int) map[int]*Player {
 		players[id] = &Player{
 			ID:            id,
 			Resources:     ResourceMap{Sheep: 0, Rock: 0, Wheat: 0, Wood: 0, Clay: 0},
+			Pieces:        pieces,
 			KnightsPlayed: 0,
 			LongestRoad:   0,
 			Points:        0,
@@ -107,7 +97,7 @@ func NewPlayers(playerIDs []int) map[int]*Player {
 	return players
 }
 
-func generateActionCardDeck() []ActionCard {
+func generateActionCardDeck(r Randomizer) []ActionCard {
 	deck := []ActionCard{}
 	// Add 14 Knights
 	for i := 0; i < 14; i++ {
@@ -133,13 +123,12 @@ func generateActionCardDeck() []ActionCard {
 		deck = append(deck, Monopoly)
 	}
 	// shuffle the deck
-	ShuffleActionCardDeck(deck)
+	ShuffleActionCardDeck(deck, r)
 	return deck
 }
 
-func ShuffleActionCardDeck(deck []ActionCard) {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-	rand.Shuffle(len(deck), func(i, j int) {
+func ShuffleActionCardDeck(deck []ActionCard, r Randomizer) {
+	shuffle(r, len(deck), func(i, j int) {
 		deck[i], deck[j] = deck[j], deck[i]
 	})
 }
@@ -0,0 +1,123 @@
+package _game_server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Lobby
+// Registry of all tables hosted by one server, tables are looked up by their game ID
+
+var (
+	ErrTableNotFound = errors.New("game not found")
+	ErrTableInPlay   = errors.New("a running game has to be abandoned before it can be deleted")
+)
+
+type Lobby struct {
+	mu     sync.RWMutex
+	nextID int
+	tables map[int]*Table
+	signer *TokenSigner // Signs the seat tokens of all tables
+	store  GameStore    // Saves the started games, nil keeps them in memory only
+}
+
+func NewLobby(signer *TokenSigner, store GameStore) *Lobby {
+	return &Lobby{nextID: 1, tables: make(map[int]*Table), signer: signer, store: store}
+}
+
+// Restore replays the games of the store, call it before serving requests.
+// Tables that were still open (not started) are not saved and do not come back.
+func (l *Lobby) Restore() error {
+	if l.store == nil {
+		return nil
+	}
+	games, err := l.store.Load()
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, stored := range games {
+		table, err := restoreTable(stored, l.signer, l.store)
+		if err != nil {
+			return fmt.Errorf("restoring game %d: %w", stored.Header.ID, err)
+		}
+		l.tables[table.id] = table
+		if table.id >= l.nextID {
+			l.nextID = table.id + 1
+		}
+	}
+	return nil
+}
+
+// Create opens a new table and assigns it the next game ID
+func (l *Lobby) Create(config TableConfig) (*Table, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	table, err := NewTable(l.nextID, config, l.signer)
+	if err != nil {
+		return nil, err
+	}
+	table.store = l.store
+	l.tables[l.nextID] = table
+	l.nextID++
+	return table, nil
+}
+
+func (l *Lobby) Get(id int) (*Table, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	table, ok := l.tables[id]
+	if !ok {
+		return nil, ErrTableNotFound
+	}
+	return table, nil
+}
+
+// List returns the tables with the given status (all tables if status is empty), by game ID
+func (l *Lobby) List(status TableStatus) []TableInfo {
+	l.mu.RLock()
+	tables := make([]*Table, 0, len(l.tables))
+	for _, table := range l.tables {
+		tables = append(tables, table)
+	}
+	l.mu.RUnlock()
+
+	infos := []TableInfo{}
+	for _, table := range tables {
+		info := table.Info()
+		if status == "" || info.Status == status {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// Delete removes a table that is not being played
+func (l *Lobby) Delete(id int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	table, ok := l.tables[id]
+	if !ok {
+		return ErrTableNotFound
+	}
+	if table.Status() == TablePlaying {
+		return ErrTableInPlay
+	}
+	if l.store != nil {
+		if err := l.store.Delete(id); err != nil {
+			return err
+		}
+	}
+	delete(l.tables, id)
+	table.close()
+	return nil
+}
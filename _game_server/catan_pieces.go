@@ -0,0 +1,68 @@
+package _game_server
+
+import "fmt"
+
+//------------------------------------------------------------------------------------------------------
+// Piece supply
+// Every build takes a piece from the player's supply, a city upgrade returns the settlement
+
+type PieceType string
+
+const (
+	PieceSettlement PieceType = "settlement"
+	PieceCity       PieceType = "city"
+	PieceRoad       PieceType = "road"
+)
+
+// PieceSupply counts the pieces a player has left to build with
+type PieceSupply struct {
+	Settlements int
+	Cities      int
+	Roads       int
+}
+
+// Pieces in the box for every player
+var StandardPieceSupply = PieceSupply{Settlements: 5, Cities: 4, Roads: 15}
+
+// SupplyError is returned when a player has no piece of the type left
+type SupplyError struct {
+	Piece PieceType
+}
+
+func (e *SupplyError) Error() string {
+	return fmt.Sprintf("no %s pieces left in your supply", e.Piece)
+}
+
+func (s *PieceSupply) count(piece PieceType) *int {
+	switch piece {
+	case PieceSettlement:
+		return &s.Settlements
+	case PieceCity:
+		return &s.Cities
+	default:
+		return &s.Roads
+	}
+}
+
+// Remaining returns how many pieces of the type are left
+func (s PieceSupply) Remaining(piece PieceType) int {
+	return *s.count(piece)
+}
+
+// checkPiece validates that the player still has a piece of the type
+func (p *Player) checkPiece(piece PieceType) error {
+	if p.Pieces.Remaining(piece) <= 0 {
+		return &SupplyError{Piece: piece}
+	}
+	return nil
+}
+
+// takePiece removes a piece from the supply (call checkPiece first)
+func (p *Player) takePiece(piece PieceType) {
+	*p.Pieces.count(piece)--
+}
+
+// returnPiece puts a piece back into the supply
+func (p *Player) returnPiece(piece PieceType) {
+	*p.Pieces.count(piece)++
+}
@@ -0,0 +1,83 @@
+package _game_server
+
+import "fmt"
+
+//------------------------------------------------------------------------------------------------------
+// Placement rules for settlements and roads
+
+type PlacementRule string
+
+const (
+	RuleOccupied    PlacementRule = "occupied"     // The corner/edge already holds a piece
+	RuleDistance    PlacementRule = "distance"     // A neighbouring corner holds a settlement/city
+	RuleConnection  PlacementRule = "connection"   // Not connected to the player's own roads
+	RuleOpeningRoad PlacementRule = "opening_road" // Opening road must touch the settlement just placed
+)
+
+// PlacementError explains which placement rule rejected a piece
+type PlacementError struct {
+	Rule           PlacementRule `json:"rule"`
+	CornerID       int           `json:"corner_id"`       // -1 for roads
+	EdgeID         int           `json:"edge_id"`         // -1 for settlements
+	ConflictCorner int           `json:"conflict_corner"` // Corner that broke the rule, -1 if none
+}
+
+func (e *PlacementError) Error() string {
+	switch e.Rule {
+	case RuleOccupied:
+		if e.EdgeID != -1 {
+			return fmt.Sprintf("edge %d already occupied", e.EdgeID)
+		}
+		return fmt.Sprintf("corner %d already occupied", e.CornerID)
+	case RuleDistance:
+		return fmt.Sprintf("corner %d is next to the building on corner %d (distance rule)", e.CornerID, e.ConflictCorner)
+	case RuleConnection:
+		if e.EdgeID != -1 {
+			return fmt.Sprintf("road on edge %d must connect to your existing network", e.EdgeID)
+		}
+		return fmt.Sprintf("settlement on corner %d must touch one of your roads", e.CornerID)
+	case RuleOpeningRoad:
+		return fmt.Sprintf("opening road must touch the settlement just placed (corner %d)", e.ConflictCorner)
+	}
+	return fmt.Sprintf("placement rule %s violated", e.Rule)
+}
+
+func settlementError(rule PlacementRule, cornerID int, conflictCorner int) *PlacementError {
+	return &PlacementError{Rule: rule, CornerID: cornerID, EdgeID: -1, ConflictCorner: conflictCorner}
+}
+
+func roadError(rule PlacementRule, edgeID int, conflictCorner int) *PlacementError {
+	return &PlacementError{Rule: rule, CornerID: -1, EdgeID: edgeID, ConflictCorner: conflictCorner}
+}
+
+// CheckSettlementPlacement validates a new settlement on an empty corner.
+// Outside the initial placement the corner must touch one of the player's roads.
+func (b *Board) CheckSettlementPlacement(playerID int, cornerID int, needsRoad bool) error {
+	corner, ok := b.Corners[cornerID]
+	if !ok {
+		return fmt.Errorf("invalid corner ID")
+	}
+
+	// 1. The corner itself must be empty
+	if corner.OwnerID != -1 {
+		return settlementError(RuleOccupied, cornerID, -1)
+	}
+
+	// 2. Distance rule: no building on any neighbouring corner
+	for _, neighborID := range corner.AdjacentCorners {
+		if b.Corners[neighborID].OwnerID != -1 {
+			return settlementError(RuleDistance, cornerID, neighborID)
+		}
+	}
+
+	// 3. Connected to one of the player's own roads
+	if needsRoad {
+		for _, edgeID := range corner.AdjacentEdges {
+			if b.Edges[edgeID].OwnerID == playerID {
+				return nil
+			}
+		}
+		return settlementError(RuleConnection, cornerID, -1)
+	}
+	return nil
+}
@@ -1,16 +1,24 @@
 package _game_server
 
-import (
-	"fmt"
-	"math/rand"
-	"time"
-)
+import "fmt"
 
 //------------------------------------------------------------------------------------------------------
 // Trading Functions
 
-// MaritimeTrade handles Player <-> Bank transactions
-func (p *Player) MaritimeTrade(give Resource, take Resource, ratio int, b *Bank) error {
+// MaritimeTrade handles Player <-> Bank transactions.
+// The ratio may not beat the player's harbors, 0 trades at the best allowed ratio.
+func (p *Player) MaritimeTrade(give Resource, take Resource, ratio int, board *Board, b *Bank) error {
+	if give == None || take == None || give == take {
+		return fmt.Errorf("invalid trade of %v for %v", give, take)
+	}
+	allowed := board.TradeRatio(p.ID, give)
+	if ratio == 0 {
+		ratio = allowed
+	}
+	if ratio < allowed {
+		return fmt.Errorf("ratio %d:1 not allowed for %v, your best ratio is %d:1", ratio, give, allowed)
+	}
+
 	if p.Resources[give] < ratio {
 		return fmt.Errorf("not enough %v to trade (need %d)", give, ratio)
 	}
@@ -75,48 +83,89 @@ func (p *Player) CanAfford(cost ResourceMap) bool {
 	return true
 }
 
+// BuildSettlementOrCity places a settlement, or upgrades the player's own settlement to a city
 func (g *GameSavestate) BuildSettlementOrCity(playerID int, cornerID int) error {
+	if corner, ok := g.Board.Corners[cornerID]; ok && corner.OwnerID == playerID && !corner.IsCity {
+		return g.BuildCity(playerID, cornerID)
+	}
+	return g.BuildSettlement(playerID, cornerID)
+}
+
+func (g *GameSavestate) BuildSettlement(playerID int, cornerID int) error {
+	if err := g.checkAction(ActionBuildSettlement, playerID); err != nil {
+		return err
+	}
 	player := g.Players[playerID]
-	corner := g.Board.Corners[cornerID]
+	corner, ok := g.Board.Corners[cornerID]
+	if !ok {
+		return fmt.Errorf("invalid corner ID")
+	}
+	if err := player.checkPiece(PieceSettlement); err != nil {
+		return err
+	}
+	if g.Phase == PhaseInitialPlacement {
+		return g.placeInitialSettlement(player, corner)
+	}
 
-	// 1. Basic Validations
-	if corner.OwnerID != -1 {
-		return fmt.Errorf("corner already occupied")
-	} else if (corner.OwnerID == playerID) && (corner.IsCity != true) {
-		// upgrade to city
-		// 2.1 Pay the bank
-		cost := Costs["city"]
-		if err := player.Pay(cost, g.Bank); err != nil {
-			return err
-		}
+	// 1. Distance rule and connection to own road
+	if err := g.Board.CheckSettlementPlacement(playerID, cornerID, true); err != nil {
+		return err
+	}
 
-		//3.1 Place the piece
-		corner.OwnerID = playerID
-		corner.IsCity = true
-		player.Points += 1
+	// 2. Pay the bank
+	cost := Costs["settlement"]
+	if err := player.Pay(cost, g.Bank); err != nil {
+		return err
+	}
 
-	} else if corner.OwnerID == -1 {
-		// place settlement
-		// 2.2 Pay the bank
-		cost := Costs["settlement"]
-		if err := player.Pay(cost, g.Bank); err != nil {
-			return err
-		}
+	// 3. Place the piece
+	corner.OwnerID = playerID
+	player.takePiece(PieceSettlement)
+	player.Points += 1
+	g.emit(EventPieceBuilt, playerID, PieceBuilt{Piece: PieceSettlement, Location: cornerID})
 
-		// 3.2 Place the piece
-		corner.OwnerID = playerID
-		player.Points += 1
+	// 4. CRITICAL: Recalculate Longest Road for EVERYONE
+	// Because this settlement might have split an opponent's path
+	g.afterAction()
+	return nil
+}
 
-		// 4. CRITICAL: Recalculate Longest Road for EVERYONE
-		// Because this settlement might have split an opponent's path
-		for _, p := range g.Players {
-			p.LongestRoad = p.GetLongestRoad(g.Board)
-		}
+// BuildCity upgrades one of the player's settlements, the settlement piece returns to the supply
+func (g *GameSavestate) BuildCity(playerID int, cornerID int) error {
+	if err := g.checkAction(ActionBuildCity, playerID); err != nil {
+		return err
+	}
+	player := g.Players[playerID]
+	corner, ok := g.Board.Corners[cornerID]
+	if !ok {
+		return fmt.Errorf("invalid corner ID")
+	}
+
+	// 1. Basic Validations
+	if corner.OwnerID != playerID {
+		return fmt.Errorf("you can only upgrade your own settlement")
+	}
+	if corner.IsCity {
+		return fmt.Errorf("corner is already a city")
+	}
+	if err := player.checkPiece(PieceCity); err != nil {
+		return err
+	}
 
-		// 5. Update the Trophy
-		g.UpdateLongestRoadTrophy()
+	// 2. Pay the bank
+	cost := Costs["city"]
+	if err := player.Pay(cost, g.Bank); err != nil {
+		return err
 	}
 
+	// 3. Swap the pieces
+	corner.IsCity = true
+	player.takePiece(PieceCity)
+	player.returnPiece(PieceSettlement)
+	player.Points += 1
+	g.emit(EventPieceBuilt, playerID, PieceBuilt{Piece: PieceCity, Location: cornerID})
+
+	g.afterAction()
 	return nil
 }
 
@@ -128,9 +177,12 @@ func (p *Player) BuildRoad(edgeID int, b *Board, bank *Bank, isFree bool) error
 		return fmt.Errorf("invalid edge ID")
 	}
 
-	// 1. Check if occupied
+	// 1. Check if occupied and if a road is left in the supply
 	if edge.OwnerID != -1 {
-		return fmt.Errorf("edge already occupied")
+		return roadError(RuleOccupied, edgeID, -1)
+	}
+	if err := p.checkPiece(PieceRoad); err != nil {
+		return err
 	}
 
 	// 2. Check Costs (unless it's from a Road Building card/starting phase)
@@ -153,10 +205,9 @@ func (p *Player) BuildRoad(edgeID int, b *Board, bank *Bank, isFree bool) error
 
 		// If the corner is empty or owned by player, check other edges touching it
 		if corner.OwnerID == -1 || corner.OwnerID == p.ID {
-			// Find all edges that touch this corner
-			for _, otherEdge := range b.Edges {
-				if (otherEdge.Corners[0] == cornerID || otherEdge.Corners[1] == cornerID) &&
-					otherEdge.OwnerID == p.ID && otherEdge.ID != edgeID {
+			// Check all edges that touch this corner
+			for _, otherEdgeID := range corner.AdjacentEdges {
+				if otherEdgeID != edgeID && b.Edges[otherEdgeID].OwnerID == p.ID {
 					connected = true
 					break
 				}
@@ -165,20 +216,33 @@ func (p *Player) BuildRoad(edgeID int, b *Board, bank *Bank, isFree bool) error
 	}
 
 	if !connected {
-		return fmt.Errorf("road must connect to your existing network")
+		return roadError(RuleConnection, edgeID, -1)
 	}
 
-	// 4. Place Road
+	// 4. Execute payment
+	if !isFree {
+		if err := p.Pay(cost, bank); err != nil {
+			return err
+		}
+	}
+
+	// 5. Place Road
 	edge.OwnerID = p.ID
-	// Execute payment
-	p.Pay(cost, bank)
+	p.takePiece(PieceRoad)
 
-	// 5. Set Longest Road of Player
+	// 6. Set Longest Road of Player
 	p.LongestRoad = p.GetLongestRoad(b)
 
 	return nil
 }
 
+// removeRoad takes a road back into the supply (undo of a free road)
+func (p *Player) removeRoad(edgeID int, b *Board) {
+	b.Edges[edgeID].OwnerID = -1
+	p.returnPiece(PieceRoad)
+	p.LongestRoad = p.GetLongestRoad(b)
+}
+
 func (p *Player) Pay(cost ResourceMap, b *Bank) error {
 	// 1. Validation Phase (Check everything before changing anything)
 	if !p.CanAfford(cost) {
@@ -197,7 +261,7 @@ func (p *Player) Pay(cost ResourceMap, b *Bank) error {
 //------------------------------------------------------------------------------------------------------
 // Action Cards Effects
 
-func (p *Player) DrawActionCard(b *Bank) (ActionCard, error) {
+func (p *Player) DrawActionCard(b *Bank, turn int) (ActionCard, error) {
 	// 1. Check cost (Sheep: 1, Wheat: 1, Rock: 1)
 	cost := Costs["actionCard"]
 	if !p.CanAfford(cost) {
@@ -219,69 +283,43 @@ func (p *Player) DrawActionCard(b *Bank) (ActionCard, error) {
 	card := b.ActionCards[len(b.ActionCards)-1]
 	b.ActionCards = b.ActionCards[:len(b.ActionCards)-1]
 
-	// 5. Add to player's hand
-	p.ActionCards = append(p.ActionCards, card)
+	// 5. Add to player's hand (not playable before the next turn)
+	p.ActionCards = append(p.ActionCards, HeldCard{Card: card, BoughtTurn: turn})
 
 	return card, nil
 }
 
-func (p *Player) PlayKnight(b *Board, targetHexID int, allPlayers map[int]*Player) (Resource, error) {
+// PlayKnight moves the Robber and robs the victim (nil if nobody can be robbed)
+func (p *Player) PlayKnight(b *Board, targetHexID int, victim *Player, r Randomizer) (Resource, error) {
 	// 1. Move the Robber
-	for id, hex := range b.Hexes {
-		if id == targetHexID && hex.HasRobber {
-			return None, fmt.Errorf("robber is already on this hex")
-		}
-		hex.HasRobber = (id == targetHexID)
+	if err := b.MoveRobber(targetHexID); err != nil {
+		return None, err
 	}
 
-	// 2. Identify potential victims (Players with settlements on this hex)
-	victimIDs := make(map[int]bool)
-	for _, corner := range b.Corners {
-		if corner.OwnerID != -1 && corner.OwnerID != p.ID {
-			for _, hexID := range corner.AdjacentHexes {
-				if hexID == targetHexID {
-					victimIDs[corner.OwnerID] = true
-				}
-			}
-		}
-	}
-
-	// 3. Logic for stealing a random card
+	// 2. Steal a random card from the chosen victim
 	var stolenRes Resource = None
-	if len(victimIDs) > 0 {
-		// Pick a random victim
-		ids := []int{}
-		for id := range victimIDs {
-			ids = append(ids, id)
-		}
-		rand.New(rand.NewSource(time.Now().UnixNano()))
-		victimID := ids[rand.Intn(len(ids))]
-		victim := allPlayers[victimID]
-
-		// Extract all resources into a slice to pick one randomly
-		hand := []Resource{}
-		for res, count := range victim.Resources {
-			for i := 0; i < count; i++ {
-				hand = append(hand, res)
-			}
-		}
-
-		if len(hand) > 0 {
-			stolenRes = hand[rand.Intn(len(hand))]
-			victim.Resources[stolenRes]--
-			p.Resources[stolenRes]++
-		}
+	if victim != nil {
+		stolenRes = p.StealResource(victim, r)
 	}
 
-	// 4. Update Knight count for Largest Army
+	// 3. Update Knight count for Largest Army
 	p.KnightsPlayed++
 
 	return stolenRes, nil
 }
 
 func (p *Player) PlayYearOfPlenty(res1 Resource, res2 Resource, b *Bank) error {
-	if b.Resources[res1] <= 0 || b.Resources[res2] <= 0 {
-		return fmt.Errorf("bank does not have requested resources")
+	if !res1.Valid() || !res2.Valid() {
+		return fmt.Errorf("invalid resource")
+	}
+	// Taking the same resource twice needs two of it in the bank
+	wanted := ResourceMap{}
+	wanted[res1]++
+	wanted[res2]++
+	for res, amount := range wanted {
+		if b.Resources[res] < amount {
+			return fmt.Errorf("bank does not have requested resources")
+		}
 	}
 
 	p.Resources[res1]++
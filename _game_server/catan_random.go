@@ -0,0 +1,64 @@
+package _game_server
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Randomness
+/*
+	Every random decision of a game (dice, deck shuffle, robber steals) is
+	drawn from the Randomizer stored on the GameSavestate. A game created from
+	the same seed and salt and fed the same actions therefore plays out
+	identically. The seed may be chosen by the players (it also picks the
+	board), the salt is drawn by the server and never shown, so nobody can
+	compute the dice and the deck in advance.
+*/
+
+type Randomizer interface {
+	// Intn returns a number in [0, n)
+	Intn(n int) int
+}
+
+// NewRandomizer returns a seeded pseudo random source
+func NewRandomizer(seed int64) Randomizer {
+	return rand.New(rand.NewSource(seed))
+}
+
+// randomSalt returns a secret salt for a new game
+func randomSalt() int64 {
+	var salt [8]byte
+	if _, err := crand.Read(salt[:]); err != nil {
+		panic(err)
+	}
+	return int64(binary.BigEndian.Uint64(salt[:]))
+}
+
+// shuffle is a Fisher-Yates shuffle that only needs Intn, so scripted
+// sources can drive it as well
+func shuffle(r Randomizer, n int, swap func(i int, j int)) {
+	for i := n - 1; i > 0; i-- {
+		swap(i, r.Intn(i+1))
+	}
+}
+
+// ScriptedRandomizer replays a fixed list of values (e.g. dice in tests)
+type ScriptedRandomizer struct {
+	Values []int
+	next   int
+}
+
+func (s *ScriptedRandomizer) Intn(n int) int {
+	if s.next >= len(s.Values) {
+		panic("scripted randomizer ran out of values")
+	}
+	value := s.Values[s.next]
+	s.next++
+	if value < 0 || value >= n {
+		panic(fmt.Sprintf("scripted value %d out of range [0, %d)", value, n))
+	}
+	return value
+}
@@ -0,0 +1,53 @@
+package _game_server
+
+import "testing"
+
+func TestScriptedRollAndSteal(t *testing.T) {
+	g, err := NewGame(1, 1, 0, []int{0, 1}, StandardRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	playOpening(t, g)
+	thief, victim := g.CurrentPlayerID, 1-g.CurrentPlayerID
+
+	// The victim holds Wood, Clay, Clay (in AllResources order), nobody has to discard
+	g.Players[thief].Resources = ResourceMap{}
+	g.Players[victim].Resources = ResourceMap{Wood: 1, Clay: 2}
+
+	// Dice 3 + 4, then the third card of the victim's hand
+	g.SetRandomizer(&ScriptedRandomizer{Values: []int{2, 3, 2}})
+
+	result, err := g.Execute(Command{Type: CommandRoll, PlayerID: thief})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Roll.Die1 != 3 || result.Roll.Die2 != 4 || g.Phase != PhaseRobber {
+		t.Fatalf("rolled %d + %d, phase %s", result.Roll.Die1, result.Roll.Die2, g.Phase)
+	}
+
+	hexID := -1
+	for _, id := range sortedIDs(g.Board.Hexes) {
+		victims := g.ValidVictims(thief, id)
+		if !g.Board.Hexes[id].HasRobber && len(victims) == 1 && victims[0] == victim {
+			hexID = id
+			break
+		}
+	}
+	if hexID == -1 {
+		t.Fatal("no hex to rob the victim on")
+	}
+
+	result, err = g.Execute(Command{Type: CommandMoveRobber, PlayerID: thief, HexID: hexID, VictimID: victim})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stolen != Clay || g.Players[thief].Resources[Clay] != 1 || g.Players[victim].Resources[Clay] != 1 {
+		t.Errorf("stole %d, thief has %v, victim has %v", result.Stolen, g.Players[thief].Resources, g.Players[victim].Resources)
+	}
+
+	// Only thief and victim learn what was stolen
+	event := g.Events[len(g.Events)-1]
+	if event.ViewFor(victim).Private != Clay || event.ViewFor(Spectator).Private != nil {
+		t.Error("the stolen resource is not private to thief and victim")
+	}
+}
@@ -0,0 +1,33 @@
+package _game_server
+
+import "errors"
+
+//------------------------------------------------------------------------------------------------------
+// Game rules
+// Settings that differ between game variants, fixed when the game is created
+
+type Rules struct {
+	PieceLimits   PieceSupply // Pieces every player starts with
+	VictoryPoints int         // Points needed to win
+}
+
+// StandardRules are the rules of the base game
+func StandardRules() Rules {
+	return Rules{
+		PieceLimits:   StandardPieceSupply,
+		VictoryPoints: 10,
+	}
+}
+
+// Validate rejects rules a game can not be played with, the opening alone
+// places two settlements and two roads
+func (r Rules) Validate() error {
+	if r.VictoryPoints <= 0 {
+		return errors.New("victory points must be positive")
+	}
+	limits := r.PieceLimits
+	if limits.Settlements < 2 || limits.Roads < 2 || limits.Cities <= 0 {
+		return errors.New("every player needs at least 2 settlements, 2 roads and 1 city")
+	}
+	return nil
+}
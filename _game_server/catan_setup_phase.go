@@ -0,0 +1,85 @@
+package _game_server
+
+import "fmt"
+
+//------------------------------------------------------------------------------------------------------
+// Initial placement (snake draft)
+/*
+	Every player places a free settlement and a free road in seat order
+	1..N, then a second pair in reverse order N..1. The road must touch the
+	settlement placed just before it. The second settlement pays out one
+	resource for every adjacent hex. The first player then starts round 1.
+*/
+
+// setupSnakeOrder returns the seat order of the opening, e.g. 1 2 3 3 2 1
+func setupSnakeOrder(turnOrder []int) []int {
+	order := append([]int{}, turnOrder...)
+	for i := len(turnOrder) - 1; i >= 0; i-- {
+		order = append(order, turnOrder[i])
+	}
+	return order
+}
+
+func (g *GameSavestate) placeInitialSettlement(player *Player, corner *Corner) error {
+	if g.SetupCornerID != -1 {
+		return fmt.Errorf("place the road for your settlement first")
+	}
+	if err := g.Board.CheckSettlementPlacement(player.ID, corner.ID, false); err != nil {
+		return err
+	}
+
+	// 1. Place the piece for free
+	corner.OwnerID = player.ID
+	player.takePiece(PieceSettlement)
+	player.Points += 1
+	g.SetupCornerID = corner.ID
+	g.emit(EventPieceBuilt, player.ID, PieceBuilt{Piece: PieceSettlement, Location: corner.ID, Free: true})
+
+	// 2. The second settlement collects its starting resources
+	if g.SetupStep >= len(g.TurnOrder) {
+		for _, hexID := range corner.AdjacentHexes {
+			res := g.Board.Hexes[hexID].ResourceType
+			if res != None && g.Bank.Resources[res] > 0 {
+				g.Bank.Resources[res]--
+				player.Resources[res]++
+			}
+		}
+	}
+	return nil
+}
+
+func (g *GameSavestate) placeInitialRoad(player *Player, edgeID int) error {
+	if g.SetupCornerID == -1 {
+		return fmt.Errorf("place your settlement first")
+	}
+	edge, ok := g.Board.Edges[edgeID]
+	if !ok {
+		return fmt.Errorf("invalid edge ID")
+	}
+	if edge.Corners[0] != g.SetupCornerID && edge.Corners[1] != g.SetupCornerID {
+		return roadError(RuleOpeningRoad, edgeID, g.SetupCornerID)
+	}
+
+	if err := player.BuildRoad(edgeID, g.Board, g.Bank, true); err != nil {
+		return err
+	}
+	g.emit(EventPieceBuilt, player.ID, PieceBuilt{Piece: PieceRoad, Location: edgeID, Free: true})
+
+	g.advanceSetup()
+	return nil
+}
+
+// advanceSetup hands the opening to the next seat of the snake, or starts the game
+func (g *GameSavestate) advanceSetup() {
+	g.SetupCornerID = -1
+	g.SetupStep++
+
+	if g.SetupStep < len(g.SetupOrder) {
+		g.CurrentPlayerID = g.SetupOrder[g.SetupStep]
+		return
+	}
+
+	g.Phase = PhaseRoll
+	g.CurrentPlayerID = g.TurnOrder[0]
+	g.Round = 1
+}
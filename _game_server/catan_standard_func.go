@@ -2,29 +2,59 @@ package _game_server
 
 import (
 	"fmt"
-	"math/rand"
-	"time"
+	"sort"
 )
 
 //------------------------------------------------------------------------------------------------------
 // Standard functions
 
-func RollDice() int {
+type DiceRoll struct {
+	Die1     int
+	Die2     int
+	Total    int
+	Round    int
+	PlayerID int
+}
 
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+// RollDice rolls two six-sided dice
+func RollDice(r Randomizer) DiceRoll {
+	die1 := r.Intn(6) + 1
+	die2 := r.Intn(6) + 1
 
-	roll := r.Intn(7) + r.Intn(7)
+	return DiceRoll{Die1: die1, Die2: die2, Total: die1 + die2}
+}
 
+// RollDice rolls for the game and records the roll in the history
+func (g *GameSavestate) RollDice() DiceRoll {
+	roll := RollDice(g.rng)
+	roll.Round = g.Round
+	roll.PlayerID = g.CurrentPlayerID
+	g.DiceHistory = append(g.DiceHistory, roll)
 	return roll
 }
 
-func (b *Board) DistributeResources(roll int, players map[int]*Player, bank *Bank) {
+// ProductionReport lists what every player received for a roll
+type ProductionReport struct {
+	Roll      int
+	Received  map[int]ResourceMap // Player ID -> resources paid out by the bank
+	Shortages []Resource          // Resources the bank could not fully cover
+}
+
+// DistributeResources pays out a roll. If the bank cannot cover every claim for
+// a resource nobody gets it, unless only one player is owed it: they get what is left.
+func (b *Board) DistributeResources(roll int, players map[int]*Player, bank *Bank) ProductionReport {
+	report := ProductionReport{Roll: roll, Received: make(map[int]ResourceMap)}
+	for id := range players {
+		report.Received[id] = ResourceMap{}
+	}
+
+	// 1. Collect every claim first, the order of the corners must not matter
+	claims := make(map[Resource]map[int]int)
 	for _, corner := range b.Corners {
 		if corner.OwnerID == -1 {
 			continue
 		}
 
-		player := players[corner.OwnerID]
 		for _, hexID := range corner.AdjacentHexes {
 			hex := b.Hexes[hexID]
 			if hex.Value == roll && !hex.HasRobber {
@@ -32,19 +62,107 @@ func (b *Board) DistributeResources(roll int, players map[int]*Player, bank *Ban
 				if corner.IsCity {
 					amount = 2
 				}
-				// Logic: Bank -> Player
-				if bank.Resources[hex.ResourceType] >= amount {
-					bank.Resources[hex.ResourceType] -= amount
-					player.Resources[hex.ResourceType] += amount
+				if claims[hex.ResourceType] == nil {
+					claims[hex.ResourceType] = make(map[int]int)
 				}
+				claims[hex.ResourceType][corner.OwnerID] += amount
 			}
 		}
 	}
+
+	// 2. Pay out per resource: Bank -> Player
+	for _, res := range AllResources {
+		owed := claims[res]
+		total := 0
+		for _, amount := range owed {
+			total += amount
+		}
+		if total == 0 {
+			continue
+		}
+
+		if total > bank.Resources[res] {
+			report.Shortages = append(report.Shortages, res)
+			if len(owed) > 1 {
+				continue // Several players are owed it: nobody gets any
+			}
+		}
+
+		for id, amount := range owed {
+			if amount > bank.Resources[res] {
+				amount = bank.Resources[res]
+			}
+			if amount == 0 {
+				continue
+			}
+			bank.Resources[res] -= amount
+			players[id].Resources[res] += amount
+			report.Received[id][res] += amount
+		}
+	}
+	return report
 }
 
 //------------------------------------------------------------------------------------------------------
 // Handle the Robber
 
+func (b *Board) MoveRobber(targetHexID int) error {
+	target, ok := b.Hexes[targetHexID]
+	if !ok {
+		return fmt.Errorf("invalid hex ID")
+	}
+	if target.HasRobber {
+		return fmt.Errorf("robber is already on this hex")
+	}
+
+	for _, hex := range b.Hexes {
+		hex.HasRobber = false
+	}
+	target.HasRobber = true
+	return nil
+}
+
+// RobberVictims returns the IDs of the players (except the thief) with a building on the hex
+func (b *Board) RobberVictims(hexID int, thiefID int) []int {
+	found := make(map[int]bool)
+	for _, corner := range b.Corners {
+		if corner.OwnerID == -1 || corner.OwnerID == thiefID {
+			continue
+		}
+		for _, id := range corner.AdjacentHexes {
+			if id == hexID {
+				found[corner.OwnerID] = true
+			}
+		}
+	}
+
+	ids := []int{}
+	for id := range found {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids) // map order is random, keep picks reproducible
+	return ids
+}
+
+// StealResource moves one random resource card from the victim to the thief
+func (p *Player) StealResource(victim *Player, r Randomizer) Resource {
+	// Extract all resources into a slice to pick one randomly
+	hand := []Resource{}
+	for _, res := range AllResources {
+		for i := 0; i < victim.Resources[res]; i++ {
+			hand = append(hand, res)
+		}
+	}
+	if len(hand) == 0 {
+		return None
+	}
+
+	stolen := hand[r.Intn(len(hand))]
+	victim.Resources[stolen]--
+	p.Resources[stolen]++
+	return stolen
+}
+
 func (p *Player) TotalResources() int {
 	total := 0
 	for _, count := range p.Resources {
@@ -61,7 +179,10 @@ func (p *Player) HandleSevenRoll(discard ResourceMap, b *Bank) error {
 
 	neededToDiscard := total / 2
 	actualDiscardCount := 0
-	for _, count := range discard {
+	for res, count := range discard {
+		if !res.Valid() || count < 0 {
+			return fmt.Errorf("invalid discard of %d x resource %d", count, res)
+		}
 		actualDiscardCount += count
 	}
 
@@ -11,6 +11,15 @@ const (
 	Clay
 )
 
+// AllResources lists the tradeable resources in a fixed order
+// (iterate over this instead of a ResourceMap when the order matters)
+var AllResources = []Resource{Sheep, Rock, Wheat, Wood, Clay}
+
+// Valid reports whether r is one of the tradeable resources (None is not)
+func (r Resource) Valid() bool {
+	return r >= Sheep && r <= Clay
+}
+
 type ActionCard int
 
 const (
@@ -23,48 +32,66 @@ const (
 
 //------------------------------------------------------------------------------------------------------
 // Boardgame
-/* ID Reference
-           ___     ___     ___
-          / 0 \___/ 1 \___/ 2 \
-          \___/   \___/   \___/
-       ___     ___     ___     ___
-      / 3 \___/ 4 \___/ 5 \___/ 6 \
-      \___/   \___/   \___/   \___/
-    ___     ___     ___     ___     ___
-   / 7 \___/ 8 \___/ 9 \___/10 \___/11 \
-   \___/   \___/   \___/   \___/   \___/
-       ___     ___     ___     ___
-      /12 \___/13 \___/14 \___/15 \
-      \___/   \___/   \___/   \___/
-           ___     ___     ___
-          /17 \___/18 \___/19 \
-          \___/   \___/   \___/
+/* ID Reference (standard board), axial coordinates (Q,R) below the IDs
+   Hex positions, neighbours and distances are defined in catan_hex_grid.go
+
+                 / \     / \     / \
+               /     \ /     \ /     \
+              |   0   |   1   |   2   |
+              | 0,-2  | 1,-2  | 2,-2  |
+             / \     / \     / \     / \
+           /     \ /     \ /     \ /     \
+          |   3   |   4   |   5   |   6   |
+          | -1,-1 | 0,-1  | 1,-1  | 2,-1  |
+         / \     / \     / \     / \     / \
+       /     \ /     \ /     \ /     \ /     \
+      |   7   |   8   |   9   |  10   |  11   |
+      | -2,0  | -1,0  |  0,0  |  1,0  |  2,0  |
+       \     / \     / \     / \     / \     /
+         \ /     \ /     \ /     \ /     \ /
+          |  12   |  13   |  14   |  15   |
+          | -2,1  | -1,1  |  0,1  |  1,1  |
+           \     / \     / \     / \     /
+             \ /     \ /     \ /     \ /
+              |  16   |  17   |  18   |
+              | -2,2  | -1,2  |  0,2  |
+               \     / \     / \     /
+                 \ /     \ /     \ /
 */
 
 type Hex struct {
 	ID           int
+	Coord        HexCoord
 	ResourceType Resource
 	Value        int // The dice number (2-12)
 	HasRobber    bool
 }
 
 type Corner struct {
-	ID            int
-	OwnerID       int // -1 if empty, otherwise Player ID
-	IsCity        bool
-	AdjacentHexes []int // IDs of hexes that touch this corner
+	ID              int
+	Coord           CornerCoord
+	OwnerID         int // -1 if empty, otherwise Player ID
+	IsCity          bool
+	AdjacentHexes   []int // IDs of hexes that touch this corner
+	AdjacentCorners []int // IDs of corners one edge away
+	AdjacentEdges   []int // IDs of edges that end in this corner
 }
 
 type Edge struct {
 	ID      int
+	Coord   EdgeCoord
 	OwnerID int
 	Corners [2]int // The two corner IDs this edge connects
 }
 
 type Board struct {
+	Seed    int64 // Seed the board was generated from (see GenerateBoard)
 	Hexes   map[int]*Hex
 	Corners map[int]*Corner
 	Edges   map[int]*Edge
+	Harbors map[int]*Harbor
+
+	hexAt map[HexCoord]int // Hex ID by coordinate
 }
 
 //------------------------------------------------------------------------------------------------------
@@ -75,10 +102,13 @@ type ResourceMap map[Resource]int
 type Player struct {
 	ID            int
 	Resources     ResourceMap
-	ActionCards   []ActionCard
+	Pieces        PieceSupply
+	ActionCards   []HeldCard
 	KnightsPlayed int
 	LongestRoad   int
-	Points        int
+	Points        int // Public points, hidden victory point cards are not included
+
+	PlayedCardThisTurn bool
 }
 
 type Bank struct {
@@ -0,0 +1,433 @@
+package _game_server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Table
+/*
+	A Table owns one game and serializes every access to it. Commands run one
+	at a time with exclusive access, queries share a read lock. The result of
+	both is JSON encoded before the lock is released, so a response never
+	contains state that another request is changing at the same time.
+
+	Lifecycle: a table is created open, players join its seats, start builds
+	the game from the seated players. It is finished once somebody won, or
+	abandoned when the players give up. Only a playing table accepts commands.
+
+	Joining a seat issues its seat token, commands are only accepted from the
+	bearer of a token of a seat at the table (see TokenSigner).
+
+	With a GameStore every command is saved before it is confirmed, a
+	command that can not be saved is rolled back (see persist).
+
+	Event streams subscribe to the table and are woken after every change,
+	they then read the new events themselves (see EventsSince). A stream that
+	falls behind misses no events, it only skips redundant wake ups.
+*/
+
+type TableStatus string
+
+const (
+	TableOpen      TableStatus = "open"      // Waiting for players
+	TablePlaying   TableStatus = "playing"   // Game in progress
+	TableFinished  TableStatus = "finished"  // Somebody won
+	TableAbandoned TableStatus = "abandoned" // Stopped before anybody won
+)
+
+const (
+	MinSeats = 2
+	MaxSeats = 4
+)
+
+var (
+	ErrTableNotPlaying  = errors.New("the game is not in progress")
+	ErrTableNotOpen     = errors.New("the game has already started")
+	ErrSeatTaken        = errors.New("the seat is already taken")
+	ErrNotEnoughPlayers = fmt.Errorf("at least %d players are needed to start", MinSeats)
+	ErrTableClosed      = errors.New("the game is already over")
+	ErrReplayBusy       = errors.New("another replay of this game is running, try again later")
+)
+
+// TableConfig is chosen when the table is created
+type TableConfig struct {
+	Seed  int64
+	Seats int
+	Rules Rules
+}
+
+// Seat is a place at the table, the seat number is the player ID in the game
+type Seat struct {
+	Seat  int    `json:"seat"`
+	Name  string `json:"name"`
+	Taken bool   `json:"taken"`
+}
+
+// TableInfo is the public summary of a table shown in the lobby
+type TableInfo struct {
+	ID     int         `json:"id"`
+	Status TableStatus `json:"status"`
+	Seed   int64       `json:"seed"` // Picks the board, see DefaultBoardOptions
+	Rules  Rules       `json:"rules"`
+	Seats  []Seat      `json:"seats"`
+}
+
+type Table struct {
+	mu     sync.RWMutex
+	id     int
+	config TableConfig
+	status TableStatus
+	seats  []Seat
+	game   *GameSavestate // nil until the table is started
+
+	signer *TokenSigner
+	key    string    // Random key of this table, part of every seat token
+	salt   int64     // Random salt of the game, see GameSavestate.Salt
+	store  GameStore // nil keeps the game in memory only
+
+	subscribers map[chan struct{}]bool
+	closed      bool // Deleted from the lobby, streams end
+
+	replaying sync.Mutex // Held while a replay runs, one at a time (see Replay)
+}
+
+func NewTable(id int, config TableConfig, signer *TokenSigner) (*Table, error) {
+	if config.Seats < MinSeats || config.Seats > MaxSeats {
+		return nil, fmt.Errorf("a table has %d to %d seats", MinSeats, MaxSeats)
+	}
+	if err := config.Rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	seats := make([]Seat, config.Seats)
+	for i := range seats {
+		seats[i].Seat = i
+	}
+	return &Table{
+		id:     id,
+		config: config,
+		status: TableOpen,
+		seats:  seats,
+		signer: signer,
+		key:    randomKey(),
+		salt:   randomSalt(),
+	}, nil
+}
+
+// Info returns the public summary of the table
+func (t *Table) Info() TableInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return TableInfo{
+		ID:     t.id,
+		Status: t.status,
+		Seed:   t.config.Seed,
+		Rules:  t.config.Rules,
+		Seats:  append([]Seat(nil), t.seats...),
+	}
+}
+
+func (t *Table) Status() TableStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.status
+}
+
+// Join takes a free seat and returns its token, the seat number becomes the player ID
+func (t *Table) Join(seat int, name string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status != TableOpen {
+		return "", ErrTableNotOpen
+	}
+	if seat < 0 || seat >= len(t.seats) {
+		return "", fmt.Errorf("invalid seat %d", seat)
+	}
+	if t.seats[seat].Taken {
+		return "", ErrSeatTaken
+	}
+	t.seats[seat].Name = name
+	t.seats[seat].Taken = true
+	return t.signer.Issue(t.id, seat, t.key), nil
+}
+
+// Authenticate returns the player ID of the seat the token was issued for
+func (t *Table) Authenticate(token string) (int, error) {
+	playerID, err := t.signer.Verify(token, t.id, t.key)
+	if err != nil {
+		return 0, err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if playerID < 0 || playerID >= len(t.seats) || !t.seats[playerID].Taken {
+		return 0, ErrInvalidToken
+	}
+	return playerID, nil
+}
+
+// CreatorToken returns the token of whoever created the table
+func (t *Table) CreatorToken() string {
+	return t.signer.Issue(t.id, CreatorID, t.key)
+}
+
+// AuthorizeDelete accepts the creator token and the token of any taken seat
+func (t *Table) AuthorizeDelete(token string) error {
+	if playerID, err := t.signer.Verify(token, t.id, t.key); err == nil && playerID == CreatorID {
+		return nil
+	}
+	_, err := t.Authenticate(token)
+	return err
+}
+
+// Start builds the game from the seated players, empty seats stay empty
+func (t *Table) Start() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status != TableOpen {
+		return ErrTableNotOpen
+	}
+	var playerIDs []int
+	for _, seat := range t.seats {
+		if seat.Taken {
+			playerIDs = append(playerIDs, seat.Seat)
+		}
+	}
+	if len(playerIDs) < MinSeats {
+		return ErrNotEnoughPlayers
+	}
+
+	game, err := NewGame(t.id, t.config.Seed, t.salt, playerIDs, t.config.Rules)
+	if err != nil {
+		return err
+	}
+	if t.store != nil {
+		header := GameHeader{ID: t.id, Seed: t.config.Seed, Salt: t.salt, Rules: t.config.Rules, Seats: t.seats, Key: t.key}
+		if err := t.store.Create(header); err != nil {
+			return fmt.Errorf("saving the game: %w", err)
+		}
+	}
+	t.game = game
+	t.status = TablePlaying
+	t.notify()
+	return nil
+}
+
+// Abandon stops an open or running table, it can not be resumed
+func (t *Table) Abandon() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status != TableOpen && t.status != TablePlaying {
+		return ErrTableClosed
+	}
+	if t.store != nil && t.game != nil {
+		if err := t.store.Abandon(t.id); err != nil {
+			return fmt.Errorf("saving the game: %w", err)
+		}
+	}
+	t.status = TableAbandoned
+	t.notify()
+	return nil
+}
+
+// Command runs fn with exclusive access to the game and encodes its result
+func (t *Table) Command(fn func(g *GameSavestate) (interface{}, error)) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status != TablePlaying {
+		return nil, ErrTableNotPlaying
+	}
+	logged := len(t.game.Commands)
+	payload, err := fn(t.game)
+	if saveErr := t.persist(logged); saveErr != nil {
+		payload, err = nil, saveErr
+	}
+	if t.game.Phase == PhaseGameOver {
+		t.status = TableFinished
+	}
+	t.notify()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(payload)
+}
+
+// persist saves the commands after the first n of the log. If that fails the
+// game is rolled back to the saved commands, t.mu must be held.
+func (t *Table) persist(n int) error {
+	if t.store == nil {
+		return nil
+	}
+	for i := n; i < len(t.game.Commands); i++ {
+		if err := t.store.Append(t.id, t.game.Commands[i]); err != nil {
+			rollback, replayErr := t.game.Replay(i)
+			if replayErr != nil {
+				return replayErr
+			}
+			t.game = rollback
+			return fmt.Errorf("saving the game: %w", err)
+		}
+	}
+	return nil
+}
+
+// restoreTable rebuilds a started table from its saved log
+func restoreTable(stored StoredGame, signer *TokenSigner, store GameStore) (*Table, error) {
+	header := stored.Header
+	var playerIDs []int
+	for _, seat := range header.Seats {
+		if seat.Taken {
+			playerIDs = append(playerIDs, seat.Seat)
+		}
+	}
+
+	game, err := ReplayGame(header.ID, header.Seed, header.Salt, playerIDs, header.Rules, stored.Commands)
+	if err != nil {
+		return nil, err
+	}
+	status := TablePlaying
+	switch {
+	case stored.Abandoned:
+		status = TableAbandoned
+	case game.Phase == PhaseGameOver:
+		status = TableFinished
+	}
+
+	return &Table{
+		id:     header.ID,
+		config: TableConfig{Seed: header.Seed, Seats: len(header.Seats), Rules: header.Rules},
+		status: status,
+		seats:  header.Seats,
+		game:   game,
+		signer: signer,
+		key:    header.Key,
+		salt:   header.Salt,
+		store:  store,
+	}, nil
+}
+
+// Query runs fn with shared (read only) access to the game and encodes its result
+func (t *Table) Query(fn func(g *GameSavestate) (interface{}, error)) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.game == nil {
+		return nil, ErrTableNotPlaying
+	}
+	payload, err := fn(t.game)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(payload)
+}
+
+// Replay rebuilds the game after its first n commands and encodes it as viewerID sees it.
+// Only copying the log holds the lock, the replay itself runs without blocking the
+// game. A table runs one replay at a time, ErrReplayBusy is returned meanwhile.
+func (t *Table) Replay(n int, viewerID int) ([]byte, error) {
+	if !t.replaying.TryLock() {
+		return nil, ErrReplayBusy
+	}
+	defer t.replaying.Unlock()
+
+	// 1. Copy what the replay needs, commands are never changed once logged
+	t.mu.RLock()
+	game := t.game
+	if game == nil {
+		t.mu.RUnlock()
+		return nil, ErrTableNotPlaying
+	}
+	if n < 0 || n > len(game.Commands) {
+		t.mu.RUnlock()
+		return nil, fmt.Errorf("the log has %d commands", len(game.Commands))
+	}
+	id, seed, salt, playerIDs, rules := game.ID, game.Seed, game.Salt, game.TurnOrder, game.Rules
+	log := game.Commands[:n:n]
+	t.mu.RUnlock()
+
+	// 2. Replay without the lock
+	replayed, err := ReplayGame(id, seed, salt, playerIDs, rules, log)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(replayed.ViewFor(viewerID))
+}
+
+//------------------------------------------------------------------------------------------------------
+// Event subscriptions
+
+// Subscribe returns a channel that receives a signal after every change of the table,
+// it is closed when the table is deleted. Call cancel once the stream ends.
+func (t *Table) Subscribe() (<-chan struct{}, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	if t.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if t.subscribers == nil {
+		t.subscribers = make(map[chan struct{}]bool)
+	}
+	t.subscribers[ch] = true
+
+	cancel := func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.subscribers, ch)
+	}
+	return ch, cancel
+}
+
+// notify wakes every subscriber, t.mu must be held
+func (t *Table) notify() {
+	for ch := range t.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default: // Already has a pending signal
+		}
+	}
+}
+
+// close ends all streams of a deleted table
+func (t *Table) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	for ch := range t.subscribers {
+		close(ch)
+	}
+	t.subscribers = nil
+}
+
+// EventsSince returns the events after seq as viewerID sees them, none before the game started
+func (t *Table) EventsSince(seq int, viewerID int) []GameEvent {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.game == nil {
+		return nil
+	}
+	return t.game.EventsSince(seq, viewerID)
+}
+
+// LastEventSeq returns the Seq of the newest event, 0 if there is none yet
+func (t *Table) LastEventSeq() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.game == nil {
+		return 0
+	}
+	return len(t.game.Events)
+}
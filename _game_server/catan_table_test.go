@@ -0,0 +1,147 @@
+package _game_server
+
+import (
+	"sort"
+	"sync"
+	"testing"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Test helpers
+
+// newTestTable returns a started table with every seat taken
+func newTestTable(t *testing.T, seed int64, seats int) (*Table, []string) {
+	t.Helper()
+	table, err := NewTable(1, TableConfig{Seed: seed, Seats: seats, Rules: StandardRules()}, NewTokenSigner([]byte("test")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := make([]string, seats)
+	for seat := range tokens {
+		if tokens[seat], err = table.Join(seat, "player"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := table.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return table, tokens
+}
+
+// nextOpeningCommand returns a valid command for the current step of the initial placement:
+// the first free corner for a settlement, then the first free edge next to it
+func nextOpeningCommand(g *GameSavestate) Command {
+	playerID := g.CurrentPlayerID
+	if g.SetupCornerID == -1 {
+		for _, cornerID := range sortedIDs(g.Board.Corners) {
+			if g.Board.CheckSettlementPlacement(playerID, cornerID, false) == nil {
+				return Command{Type: CommandBuildSettlement, PlayerID: playerID, CornerID: cornerID}
+			}
+		}
+	}
+	for _, edgeID := range g.Board.Corners[g.SetupCornerID].AdjacentEdges {
+		if g.Board.Edges[edgeID].OwnerID == -1 {
+			return Command{Type: CommandBuildRoad, PlayerID: playerID, EdgeID: edgeID}
+		}
+	}
+	return Command{}
+}
+
+// playOpening executes the whole initial placement
+func playOpening(t *testing.T, g *GameSavestate) {
+	t.Helper()
+	for g.Phase == PhaseInitialPlacement {
+		if _, err := g.Execute(nextOpeningCommand(g)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func sortedIDs[T any](m map[int]T) []int {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+//------------------------------------------------------------------------------------------------------
+// Concurrent access, run with go test -race
+
+func TestTableConcurrentAccess(t *testing.T) {
+	table, _ := newTestTable(t, 7, 3)
+	done := make(chan struct{})
+
+	// 1. One writer plays the opening and a few turns, every command goes through the table
+	var writer sync.WaitGroup
+	writer.Add(1)
+	go func() {
+		defer writer.Done()
+		defer close(done)
+		for turns := 0; turns < 12; {
+			_, err := table.Command(func(g *GameSavestate) (interface{}, error) {
+				switch g.Phase {
+				case PhaseInitialPlacement:
+					return g.Execute(nextOpeningCommand(g))
+				case PhaseRoll:
+					return g.Execute(Command{Type: CommandRoll, PlayerID: g.CurrentPlayerID})
+				case PhaseTradeBuild:
+					turns++
+					return g.Execute(Command{Type: CommandEndTurn, PlayerID: g.CurrentPlayerID})
+				}
+				turns = 12 // A 7 was rolled, the robber is not part of this test
+				return nil, nil
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	// 2. Readers query views and events of every seat and of a spectator meanwhile
+	var readers sync.WaitGroup
+	for viewerID := Spectator; viewerID < 3; viewerID++ {
+		readers.Add(1)
+		go func(viewerID int) {
+			defer readers.Done()
+			signal, cancel := table.Subscribe()
+			defer cancel()
+			seen := 0
+			for {
+				if _, err := table.Query(func(g *GameSavestate) (interface{}, error) {
+					return g.ViewFor(viewerID), nil
+				}); err != nil {
+					t.Error(err)
+					return
+				}
+				for _, event := range table.EventsSince(seen, viewerID) {
+					if event.Seq != seen+1 {
+						t.Errorf("viewer %d got event %d after %d", viewerID, event.Seq, seen)
+						return
+					}
+					seen = event.Seq
+				}
+				select {
+				case <-done:
+					return
+				case <-signal:
+				}
+			}
+		}(viewerID)
+	}
+
+	writer.Wait()
+	readers.Wait()
+
+	events, err := table.Query(func(g *GameSavestate) (interface{}, error) {
+		if g.Round == 0 {
+			t.Error("the opening was not finished")
+		}
+		return len(g.Events), nil
+	})
+	if err != nil || string(events) == "0" {
+		t.Errorf("no events recorded (%v)", err)
+	}
+}
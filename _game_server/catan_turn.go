@@ -0,0 +1,164 @@
+package _game_server
+
+import (
+	"fmt"
+	"sort"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Turn and phase state machine
+/*
+	InitialPlacement --(snake draft done, see catan_setup_phase.go)--> Roll
+	Roll --(roll != 7)--> TradeBuild
+	Roll --(roll == 7)--> Robber --(discards done, robber moved)--> TradeBuild
+	TradeBuild --(EndTurn)--> EndTurn --(next player)--> Roll
+	any phase --(active player reaches the point target)--> GameOver
+
+	EndTurn only lasts while the end of turn bookkeeping runs.
+	Every game level action checks the phase and the active player first.
+*/
+
+type Phase int
+
+const (
+	PhaseInitialPlacement Phase = iota
+	PhaseRoll
+	PhaseRobber // Discards and moving the Robber after a 7
+	PhaseTradeBuild
+	PhaseEndTurn
+	PhaseGameOver // Somebody won, see GameSavestate.Results
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseInitialPlacement:
+		return "initial placement"
+	case PhaseRoll:
+		return "roll"
+	case PhaseRobber:
+		return "robber"
+	case PhaseTradeBuild:
+		return "trade/build"
+	case PhaseEndTurn:
+		return "end turn"
+	case PhaseGameOver:
+		return "game over"
+	}
+	return fmt.Sprintf("phase(%d)", int(p))
+}
+
+type Action string
+
+const (
+	ActionRoll            Action = "roll"
+	ActionDiscard         Action = "discard"
+	ActionMoveRobber      Action = "move robber"
+	ActionBuildSettlement Action = "build settlement"
+	ActionBuildCity       Action = "build city"
+	ActionBuildRoad       Action = "build road"
+	ActionMaritimeTrade   Action = "maritime trade"
+	ActionPlayerTrade     Action = "player trade"
+	ActionAcceptTrade     Action = "accept trade"
+	ActionBuyActionCard   Action = "buy action card"
+	ActionPlayActionCard  Action = "play action card"
+	ActionEndTurn         Action = "end turn"
+)
+
+// Actions any player may take, not only the active one
+var offTurnActions = map[Action]bool{
+	ActionDiscard:     true,
+	ActionAcceptTrade: true,
+}
+
+// Phases in which the active player may take an action
+var allowedPhases = map[Action][]Phase{
+	ActionRoll:            {PhaseRoll},
+	ActionDiscard:         {PhaseRobber},
+	ActionMoveRobber:      {PhaseRobber},
+	ActionBuildSettlement: {PhaseInitialPlacement, PhaseTradeBuild},
+	ActionBuildCity:       {PhaseTradeBuild},
+	ActionBuildRoad:       {PhaseInitialPlacement, PhaseTradeBuild},
+	ActionMaritimeTrade:   {PhaseTradeBuild},
+	ActionPlayerTrade:     {PhaseTradeBuild},
+	ActionAcceptTrade:     {PhaseTradeBuild},
+	ActionBuyActionCard:   {PhaseTradeBuild},
+	ActionPlayActionCard:  {PhaseRoll, PhaseTradeBuild},
+	ActionEndTurn:         {PhaseTradeBuild},
+}
+
+// PhaseError is returned for actions that are not allowed in the current phase
+type PhaseError struct {
+	Action Action
+	Phase  Phase
+}
+
+func (e *PhaseError) Error() string {
+	return fmt.Sprintf("%s is not allowed in phase %s", e.Action, e.Phase)
+}
+
+// TurnError is returned when a player acts outside of their turn
+type TurnError struct {
+	PlayerID        int
+	CurrentPlayerID int
+}
+
+func (e *TurnError) Error() string {
+	return fmt.Sprintf("player %d cannot act, it is the turn of player %d", e.PlayerID, e.CurrentPlayerID)
+}
+
+// checkAction validates that playerID may take the action right now
+func (g *GameSavestate) checkAction(action Action, playerID int) error {
+	if g.Phase == PhaseGameOver {
+		return ErrGameOver
+	}
+	if _, ok := g.Players[playerID]; !ok {
+		return fmt.Errorf("invalid player ID")
+	}
+
+	allowed := false
+	for _, phase := range allowedPhases[action] {
+		if phase == g.Phase {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return &PhaseError{Action: action, Phase: g.Phase}
+	}
+
+	if playerID != g.CurrentPlayerID && !offTurnActions[action] {
+		return &TurnError{PlayerID: playerID, CurrentPlayerID: g.CurrentPlayerID}
+	}
+	return nil
+}
+
+// setupTurnOrder seats the players in ascending ID order, the first one starts
+func (g *GameSavestate) setupTurnOrder() {
+	g.TurnOrder = []int{}
+	for id := range g.Players {
+		g.TurnOrder = append(g.TurnOrder, id)
+	}
+	sort.Ints(g.TurnOrder)
+
+	g.Round = 0 // The opening is not part of round 1
+	g.Phase = PhaseInitialPlacement
+	g.SetupOrder = setupSnakeOrder(g.TurnOrder)
+	g.SetupStep = 0
+	g.SetupCornerID = -1
+	g.CurrentPlayerID = -1
+	if len(g.TurnOrder) > 0 {
+		g.CurrentPlayerID = g.TurnOrder[0]
+	}
+}
+
+// nextPlayer hands the turn to the next seat, returns true if a new round started
+func (g *GameSavestate) nextPlayer() bool {
+	for i, id := range g.TurnOrder {
+		if id == g.CurrentPlayerID {
+			next := (i + 1) % len(g.TurnOrder)
+			g.CurrentPlayerID = g.TurnOrder[next]
+			return next == 0
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package _game_server
+
+import (
+	"errors"
+	"sort"
+)
+
+//------------------------------------------------------------------------------------------------------
+// Victory
+/*
+	Only the active player can win, the moment their points (including hidden
+	victory point cards) reach Rules.VictoryPoints. The game then moves to
+	PhaseGameOver, Results is published and every further action is refused.
+*/
+
+var ErrGameOver = errors.New("the game is over, no further actions are accepted")
+
+type PlayerResult struct {
+	PlayerID          int
+	Settlements       int
+	Cities            int
+	LongestRoad       bool
+	LargestArmy       bool
+	VictoryPointCards int
+	Points            int // Total including trophies and victory point cards
+}
+
+type GameResults struct {
+	WinnerID int
+	Round    int
+	Turn     int
+	Players  []PlayerResult // Highest points first
+}
+
+// checkVictory ends the game if the active player reached the point target
+func (g *GameSavestate) checkVictory() {
+	if g.Phase == PhaseGameOver || g.Phase == PhaseInitialPlacement {
+		return
+	}
+	player, ok := g.Players[g.CurrentPlayerID]
+	if !ok || player.TotalPoints() < g.Rules.VictoryPoints {
+		return
+	}
+
+	g.Phase = PhaseGameOver
+	g.Results = g.buildResults(player.ID)
+	g.emit(EventGameWon, player.ID, g.Results)
+}
+
+func (g *GameSavestate) buildResults(winnerID int) *GameResults {
+	results := &GameResults{WinnerID: winnerID, Round: g.Round, Turn: g.Turn}
+
+	for _, id := range g.TurnOrder {
+		player := g.Players[id]
+		entry := PlayerResult{
+			PlayerID:          id,
+			LongestRoad:       g.LongestRoadTrophy.OwnerID == id,
+			LargestArmy:       g.LargestArmyTrophy.OwnerID == id,
+			VictoryPointCards: player.VictoryPointCards(),
+			Points:            player.TotalPoints(),
+		}
+		for _, corner := range g.Board.Corners {
+			if corner.OwnerID != id {
+				continue
+			}
+			if corner.IsCity {
+				entry.Cities++
+			} else {
+				entry.Settlements++
+			}
+		}
+		results.Players = append(results.Players, entry)
+	}
+
+	sort.SliceStable(results.Players, func(i, j int) bool {
+		return results.Players[i].Points > results.Players[j].Points
+	})
+	return results
+}
@@ -0,0 +1,131 @@
+package _game_server
+
+//------------------------------------------------------------------------------------------------------
+// Views
+/*
+	A view is the part of the game one viewer is allowed to see. Players see
+	their own hand in full, of their opponents only how many cards they hold,
+	the knights they played and their public points. A spectator sees public
+	information only. The order of the action card deck and the salt of the
+	game (which decides deck and dice) are never shown. The seed only picks
+	the board, it is public so a board can be shared and generated again.
+*/
+
+// Spectator is the viewer ID of somebody without a seat
+const Spectator = -1
+
+type PlayerView struct {
+	ID            int
+	ResourceCount int
+	CardCount     int
+	Pieces        PieceSupply
+	KnightsPlayed int
+	LongestRoad   int
+	Points        int // Public points
+
+	PlayedCardThisTurn bool
+
+	// Only in the player's own view
+	Resources   ResourceMap `json:",omitempty"`
+	ActionCards []HeldCard  `json:",omitempty"`
+	TotalPoints int         `json:",omitempty"` // Including hidden victory point cards
+}
+
+type BankView struct {
+	Resources   ResourceMap
+	ActionCards int // Cards left in the deck
+}
+
+type GameView struct {
+	ID       int
+	ViewerID int // Spectator if the viewer has no seat
+	Rules    Rules
+
+	Round           int
+	Turn            int
+	Phase           string
+	TurnOrder       []int
+	CurrentPlayerID int
+
+	SetupOrder    []int
+	SetupStep     int
+	SetupCornerID int
+
+	Board       *Board
+	Bank        BankView
+	Players     map[int]PlayerView
+	DiceHistory []DiceRoll
+
+	PendingDiscards map[int]int
+	TradeOffers     map[int]TradeOffer
+
+	Results *GameResults
+
+	LongestRoadTrophy Trophy
+	LargestArmyTrophy Trophy
+}
+
+// ViewFor returns what viewerID may see of the game, unknown IDs get the spectator view
+func (g *GameSavestate) ViewFor(viewerID int) GameView {
+	if _, ok := g.Players[viewerID]; !ok {
+		viewerID = Spectator
+	}
+
+	return GameView{
+		ID:       g.ID,
+		ViewerID: viewerID,
+		Rules:    g.Rules,
+
+		Round:           g.Round,
+		Turn:            g.Turn,
+		Phase:           g.Phase.String(),
+		TurnOrder:       g.TurnOrder,
+		CurrentPlayerID: g.CurrentPlayerID,
+
+		SetupOrder:    g.SetupOrder,
+		SetupStep:     g.SetupStep,
+		SetupCornerID: g.SetupCornerID,
+
+		Board:       g.Board,
+		Bank:        BankView{Resources: g.Bank.Resources, ActionCards: len(g.Bank.ActionCards)},
+		Players:     g.PlayerViews(viewerID),
+		DiceHistory: g.DiceHistory,
+
+		PendingDiscards: g.PendingDiscards,
+		TradeOffers:     g.TradeOffers,
+
+		Results: g.Results,
+
+		LongestRoadTrophy: g.LongestRoadTrophy,
+		LargestArmyTrophy: g.LargestArmyTrophy,
+	}
+}
+
+// PlayerViews returns every player as viewerID sees them
+func (g *GameSavestate) PlayerViews(viewerID int) map[int]PlayerView {
+	views := make(map[int]PlayerView, len(g.Players))
+	for id, player := range g.Players {
+		views[id] = player.viewFor(viewerID)
+	}
+	return views
+}
+
+func (p *Player) viewFor(viewerID int) PlayerView {
+	view := PlayerView{
+		ID:            p.ID,
+		ResourceCount: p.TotalResources(),
+		CardCount:     len(p.ActionCards),
+		Pieces:        p.Pieces,
+		KnightsPlayed: p.KnightsPlayed,
+		LongestRoad:   p.LongestRoad,
+		Points:        p.Points,
+
+		PlayedCardThisTurn: p.PlayedCardThisTurn,
+	}
+	if viewerID == p.ID {
+		view.Resources = p.Resources
+		view.ActionCards = p.ActionCards
+		view.TotalPoints = p.TotalPoints()
+	}
+	return view
+}
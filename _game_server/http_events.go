@@ -0,0 +1,204 @@
+package _game_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//------------------------------------------------------------------------------------------------
+// Event streams
+/*
+	Clients subscribe to the events of a game instead of polling /status.
+	Every event is filtered through the view of the receiving player (the
+	seat token), without a token the spectator view is used. Browsers can
+	not set headers on a WebSocket or an EventSource, so the token may also
+	be passed as ?token=... . A reconnecting client resumes with
+	?since=<last seq> (Server-Sent Events: the Last-Event-ID header), a seq
+	after the last event of the game is rejected with 400.
+
+	/games/{id}/ws      WebSocket, one JSON text frame per event
+	/games/{id}/events  Server-Sent Events, for clients behind proxies that break WebSockets
+*/
+
+const (
+	streamPingInterval = 30 * time.Second
+	sseRetry           = 3 * time.Second // Reconnect delay suggested to EventSource clients
+)
+
+// streamViewer is the viewer of a stream, the token comes from the header or the query
+func streamViewer(table *Table, r *http.Request) (int, error) {
+	token := bearerToken(r)
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return Spectator, nil
+	}
+	return table.Authenticate(token)
+}
+
+// parseSince reads the sequence number to resume after, 0 streams all events
+func parseSince(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	seq, err := strconv.Atoi(value)
+	if err != nil || seq < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return seq, nil
+}
+
+// checkSince rejects resuming after an event the game does not have (yet)
+func checkSince(table *Table, since int) error {
+	if last := table.LastEventSeq(); since > last {
+		return fmt.Errorf("since %d is after the last event %d", since, last)
+	}
+	return nil
+}
+
+// streamEvents sends every event after since until the client leaves (done) or the table is deleted.
+// ping keeps idle connections open. Returns false if the table was deleted.
+func streamEvents(table *Table, viewerID int, since int, notify <-chan struct{}, done <-chan struct{},
+	send func(GameEvent) error, ping func() error) bool {
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		// 1. Send what is new
+		for _, event := range table.EventsSince(since, viewerID) {
+			if err := send(event); err != nil {
+				return true
+			}
+			since = event.Seq
+		}
+
+		// 2. Wait for the next change
+		select {
+		case _, open := <-notify:
+			if !open {
+				return false
+			}
+		case <-done:
+			return true
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return true
+			}
+		}
+	}
+}
+
+// handleEventSocket streams the events of a game over a WebSocket, e.g. /games/1/ws?since=12
+func (s *Server) handleEventSocket(w http.ResponseWriter, r *http.Request) {
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	viewerID, err := streamViewer(table, r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	since, err := parseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := checkSince(table, since); err != nil {
+		respondWithError(w, err)
+		return
+	}
+
+	// 1. Subscribe before the handshake, so no change slips through
+	notify, cancel := table.Subscribe()
+	defer cancel()
+
+	ws := upgradeWebSocket(w, r)
+	if ws == nil {
+		return
+	}
+	defer ws.conn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		ws.readLoop()
+		close(done)
+	}()
+
+	// 2. Stream until the client leaves
+	send := func(event GameEvent) error { return ws.writeJSON(event) }
+	ping := func() error { return ws.writeFrame(wsPing, nil) }
+	if !streamEvents(table, viewerID, since, notify, done, send, ping) {
+		ws.writeClose(wsCloseGoingAway)
+	}
+}
+
+// handleEventFeed streams the events of a game as Server-Sent Events, e.g. /games/1/events?since=12
+func (s *Server) handleEventFeed(w http.ResponseWriter, r *http.Request) {
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	viewerID, err := streamViewer(table, r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	// A reconnecting EventSource sends the ID of the last event it got
+	resume := r.Header.Get("Last-Event-ID")
+	if resume == "" {
+		resume = r.URL.Query().Get("since")
+	}
+	since, err := parseSince(resume)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := checkSince(table, since); err != nil {
+		respondWithError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// 1. Subscribe, then open the stream
+	notify, cancel := table.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep reverse proxies from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	flusher.Flush()
+
+	// 2. Stream until the client leaves, heartbeats are comments
+	send := func(event GameEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	ping := func() error {
+		if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	streamEvents(table, viewerID, since, notify, r.Context().Done(), send, ping)
+}
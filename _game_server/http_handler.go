@@ -2,56 +2,260 @@ package _game_server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type Server struct {
-	GameSaveState *GameSavestate
+	Lobby *Lobby
 }
 
+var errGameRunning = errors.New("the game is still running")
+
 // Helper to send JSON responses
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
+	respondWithBody(w, code, response)
+}
+
+// Helper to send an already encoded JSON response
+func respondWithBody(w http.ResponseWriter, code int, response []byte) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	w.Write(response)
 }
 
+// Helper to send rule errors, actions out of phase/turn are a conflict with the game state
+func respondWithError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	var phaseErr *PhaseError
+	var turnErr *TurnError
+	var supplyErr *SupplyError
+	if errors.As(err, &phaseErr) || errors.As(err, &turnErr) || errors.As(err, &supplyErr) || errors.Is(err, ErrGameOver) {
+		code = http.StatusConflict
+	}
+	// Table lifecycle
+	switch err {
+	case ErrTableNotPlaying, ErrTableNotOpen, ErrTableClosed, ErrTableInPlay, ErrSeatTaken, ErrNotEnoughPlayers, ErrNothingToUndo, errGameRunning:
+		code = http.StatusConflict
+	case ErrTableNotFound:
+		code = http.StatusNotFound
+	case ErrInvalidToken:
+		code = http.StatusUnauthorized
+	case ErrReplayBusy:
+		code = http.StatusTooManyRequests
+	}
+
+	// Placement errors also report which rule failed
+	var placementErr *PlacementError
+	if errors.As(err, &placementErr) {
+		respondWithJSON(w, code, map[string]interface{}{"error": err.Error(), "placement": placementErr})
+		return
+	}
+	respondWithJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// table looks up the table of the game ID in the path (/games/{id}/...)
+func (s *Server) table(r *http.Request) (*Table, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return nil, ErrTableNotFound
+	}
+	return s.Lobby.Get(id)
+}
+
+// bearerToken returns the seat token of the Authorization header ("Bearer <token>")
+func bearerToken(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token
+}
+
+// viewer returns the player the token of the request belongs to, Spectator if there is no token
+func viewer(table *Table, r *http.Request) (int, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return Spectator, nil
+	}
+	return table.Authenticate(token)
+}
+
+// command runs a state changing action on the table of the request (one at a time),
+// the acting player is the one the seat token was issued to
+func (s *Server) command(w http.ResponseWriter, r *http.Request, fn func(g *GameSavestate, playerID int) (interface{}, error)) {
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	playerID, err := table.Authenticate(bearerToken(r))
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	response, err := table.Command(func(g *GameSavestate) (interface{}, error) {
+		return fn(g, playerID)
+	})
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	respondWithBody(w, http.StatusOK, response)
+}
+
+// query reads a consistent snapshot of the table of the request, as seen by the viewer of the request
+func (s *Server) query(w http.ResponseWriter, r *http.Request, fn func(g *GameSavestate, viewerID int) (interface{}, error)) {
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	viewerID, err := viewer(table, r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	response, err := table.Query(func(g *GameSavestate) (interface{}, error) {
+		return fn(g, viewerID)
+	})
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	respondWithBody(w, http.StatusOK, response)
+}
+
+var success = map[string]string{"message": "Success"}
+
 //--------------------------------------------------------------------------------------------
 
+// The acting player of every action is taken from the seat token (Authorization: Bearer <token>).
+// Actions run as commands (see Execute), so every accepted action ends up in the command log.
+
+// execute runs a command that only reports success
+func execute(g *GameSavestate, cmd Command) (interface{}, error) {
+	if _, err := g.Execute(cmd); err != nil {
+		return nil, err
+	}
+	return success, nil
+}
+
 func (s *Server) handleRollDice(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		// 1. Roll 2d6 (recorded in the dice history) and hand out resources
+		result, err := g.Execute(Command{Type: CommandRoll, PlayerID: playerID})
+		if err != nil {
+			return nil, err
+		}
+
+		// 2. Return the result (after a 7 the listed players have to discard first)
+		return map[string]interface{}{
+			"roll":             result.Roll,
+			"production":       result.Production,
+			"phase":            g.Phase.String(),
+			"pending_discards": g.PendingDiscards,
+			"players":          g.PlayerViews(playerID),
+		}, nil
+	})
+}
+
+type DiscardRequest struct {
+	Resources ResourceMap `json:"resources"`
+}
+
+func (s *Server) handleDiscard(w http.ResponseWriter, r *http.Request) {
+	var req DiscardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
-	// 1. Simulate roll (2d6)
-	roll := RollDice()
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		if _, err := g.Execute(Command{Type: CommandDiscard, PlayerID: playerID, Resources: req.Resources}); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"pending_discards": g.PendingDiscards}, nil
+	})
+}
 
-	// 2. Run your existing logic
-	s.GameSaveState.Board.DistributeResources(roll, s.GameSaveState.Players, s.GameSaveState.Bank)
+type RobberRequest struct {
+	HexID    int `json:"hex_id"`
+	VictimID int `json:"victim_id"` // -1 if nobody can be robbed
+}
+
+func (s *Server) handleMoveRobber(w http.ResponseWriter, r *http.Request) {
+	var req RobberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
 
-	// 3. Return the result
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"roll":    roll,
-		"players": s.GameSaveState.Players,
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		result, err := g.Execute(Command{Type: CommandMoveRobber, PlayerID: playerID, HexID: req.HexID, VictimID: req.VictimID})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"stolen": result.Stolen}, nil
 	})
 }
 
-func (s *Server) handleGetStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleRobberVictims lists who the player of the token may rob on a hex, e.g. /games/1/robber/victims?hex_id=4
+func (s *Server) handleRobberVictims(w http.ResponseWriter, r *http.Request) {
+	hexID, err := strconv.Atoi(r.URL.Query().Get("hex_id"))
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
-	// Return the entire Game object
-	respondWithJSON(w, http.StatusOK, s.GameSaveState)
+	s.query(w, r, func(g *GameSavestate, viewerID int) (interface{}, error) {
+		if _, ok := g.Board.Hexes[hexID]; !ok {
+			return nil, errors.New("invalid hex ID")
+		}
+		return map[string]interface{}{"victims": g.ValidVictims(viewerID, hexID)}, nil
+	})
+}
+
+func (s *Server) handleEndTurn(w http.ResponseWriter, r *http.Request) {
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		if _, err := g.Execute(Command{Type: CommandEndTurn, PlayerID: playerID}); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"current_player": g.CurrentPlayerID,
+			"round":          g.Round,
+			"phase":          g.Phase.String(),
+		}, nil
+	})
+}
+
+// handleGetStatus returns the game as the player of the token sees it, without a token the spectator view
+func (s *Server) handleGetStatus(w http.ResponseWriter, r *http.Request) {
+	s.query(w, r, func(g *GameSavestate, viewerID int) (interface{}, error) {
+		return g.ViewFor(viewerID), nil
+	})
+}
+
+func (s *Server) handleDiceStats(w http.ResponseWriter, r *http.Request) {
+
+	s.query(w, r, func(g *GameSavestate, viewerID int) (interface{}, error) {
+		return ComputeDiceStats(g.DiceHistory), nil
+	})
+}
+
+func (s *Server) handleGetResults(w http.ResponseWriter, r *http.Request) {
+
+	s.query(w, r, func(g *GameSavestate, viewerID int) (interface{}, error) {
+		if g.Results == nil {
+			return nil, errGameRunning
+		}
+		return g.Results, nil
+	})
 }
 
 //------------------------------------------------------------------------------------------------
 
 type BuildRequest struct {
-	PlayerID int `json:"player_id"`
 	CornerID int `json:"corner_id"`
 }
 
@@ -63,11 +267,230 @@ func (s *Server) handleBuildSettlement(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Execute the Game logic we built earlier
-	err := s.GameSaveState.BuildSettlementOrCity(req.PlayerID, req.CornerID)
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		return execute(g, Command{Type: CommandBuildSettlement, PlayerID: playerID, CornerID: req.CornerID})
+	})
+}
+
+func (s *Server) handleBuildCity(w http.ResponseWriter, r *http.Request) {
+	var req BuildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		return execute(g, Command{Type: CommandBuildCity, PlayerID: playerID, CornerID: req.CornerID})
+	})
+}
+
+type RoadRequest struct {
+	EdgeID int `json:"edge_id"`
+}
+
+func (s *Server) handleBuildRoad(w http.ResponseWriter, r *http.Request) {
+	var req RoadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		return execute(g, Command{Type: CommandBuildRoad, PlayerID: playerID, EdgeID: req.EdgeID})
+	})
+}
+
+//------------------------------------------------------------------------------------------------
+
+type MaritimeTradeRequest struct {
+	Give  Resource `json:"give"`
+	Take  Resource `json:"take"`
+	Ratio int      `json:"ratio"` // Optional, 0 uses the best ratio of the player's harbors
+}
+
+func (s *Server) handleMaritimeTrade(w http.ResponseWriter, r *http.Request) {
+	var req MaritimeTradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		return execute(g, Command{Type: CommandMaritimeTrade, PlayerID: playerID, Give: req.Give, Take: req.Take, Ratio: req.Ratio})
+	})
+}
+
+type TradeOfferRequest struct {
+	ReceiverID int         `json:"receiver_id"`
+	Give       ResourceMap `json:"give"`
+	Receive    ResourceMap `json:"receive"`
+}
+
+func (s *Server) handleProposeTrade(w http.ResponseWriter, r *http.Request) {
+	var req TradeOfferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		offer := &TradeOffer{ReceiverID: req.ReceiverID, Give: req.Give, Receive: req.Receive}
+		result, err := g.Execute(Command{Type: CommandProposeTrade, PlayerID: playerID, Offer: offer})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"offer_id": result.OfferID}, nil
+	})
+}
+
+type TradeAcceptRequest struct {
+	OfferID int `json:"offer_id"`
+}
+
+func (s *Server) handleAcceptTrade(w http.ResponseWriter, r *http.Request) {
+	var req TradeAcceptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		return execute(g, Command{Type: CommandAcceptTrade, PlayerID: playerID, OfferID: req.OfferID})
+	})
+}
+
+//------------------------------------------------------------------------------------------------
+// Action cards
+
+func (s *Server) handleBuyActionCard(w http.ResponseWriter, r *http.Request) {
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		result, err := g.Execute(Command{Type: CommandBuyActionCard, PlayerID: playerID})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"card": result.Card}, nil
+	})
+}
+
+func (s *Server) handlePlayKnight(w http.ResponseWriter, r *http.Request) {
+	var req RobberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		result, err := g.Execute(Command{Type: CommandPlayKnight, PlayerID: playerID, HexID: req.HexID, VictimID: req.VictimID})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"stolen": result.Stolen}, nil
+	})
+}
+
+type YearOfPlentyRequest struct {
+	Resource1 Resource `json:"resource1"`
+	Resource2 Resource `json:"resource2"`
+}
+
+func (s *Server) handlePlayYearOfPlenty(w http.ResponseWriter, r *http.Request) {
+	var req YearOfPlentyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		return execute(g, Command{Type: CommandPlayYearOfPlenty, PlayerID: playerID, Picks: []Resource{req.Resource1, req.Resource2}})
+	})
+}
+
+type MonopolyRequest struct {
+	Resource Resource `json:"resource"`
+}
+
+func (s *Server) handlePlayMonopoly(w http.ResponseWriter, r *http.Request) {
+	var req MonopolyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		result, err := g.Execute(Command{Type: CommandPlayMonopoly, PlayerID: playerID, Resource: req.Resource})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"stolen": result.Taken}, nil
+	})
+}
+
+type RoadBuildingRequest struct {
+	EdgeIDs []int `json:"edge_ids"`
+}
+
+func (s *Server) handlePlayRoadBuilding(w http.ResponseWriter, r *http.Request) {
+	var req RoadBuildingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		return execute(g, Command{Type: CommandPlayRoadBuilding, PlayerID: playerID, EdgeIDs: req.EdgeIDs})
+	})
+}
+
+//------------------------------------------------------------------------------------------------
+// Command log: undo, audit and replay
+
+// handleUndo takes back the last action of the player, if it revealed nothing new
+func (s *Server) handleUndo(w http.ResponseWriter, r *http.Request) {
+	s.command(w, r, func(g *GameSavestate, playerID int) (interface{}, error) {
+		return execute(g, Command{Type: CommandUndo, PlayerID: playerID})
+	})
+}
+
+// handleGetLog returns the accepted commands after ?since=<seq>, discarded cards only to their owner
+func (s *Server) handleGetLog(w http.ResponseWriter, r *http.Request) {
+	since, err := parseSince(r.URL.Query().Get("since"))
 	if err != nil {
-		respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Success"})
+	s.query(w, r, func(g *GameSavestate, viewerID int) (interface{}, error) {
+		commands := []Command{}
+		for _, cmd := range g.Commands[min(since, len(g.Commands)):] {
+			commands = append(commands, cmd.ViewFor(viewerID))
+		}
+		return commands, nil
+	})
+}
+
+// handleReplay returns the game as it was after the first ?seq=<n> commands, seen by
+// the seated player of the token (replays are expensive, spectators can not ask for them)
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	seq, err := strconv.Atoi(r.URL.Query().Get("seq"))
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	playerID, err := table.Authenticate(bearerToken(r))
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	response, err := table.Replay(seq, playerID)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	respondWithBody(w, http.StatusOK, response)
 }
@@ -0,0 +1,159 @@
+package _game_server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+//------------------------------------------------------------------------------------------------
+// Lobby endpoints
+// Create, list, join, start, abandon and delete tables, the game itself is played on /games/{id}/...
+// Joining returns the seat token, start, abandon and delete need the token of a seat at the table
+
+type CreateGameRequest struct {
+	Seed  *int64 `json:"seed"`  // Optional, a random seed is used if missing
+	Seats int    `json:"seats"` // Optional, defaults to MaxSeats
+	Rules *Rules `json:"rules"` // Optional, the fields sent replace those of StandardRules
+}
+
+// CreateGameResponse is the new table with the creator token, needed to delete the table
+type CreateGameResponse struct {
+	TableInfo
+	Token string `json:"token"`
+}
+
+func (s *Server) handleCreateGame(w http.ResponseWriter, r *http.Request) {
+	// Rules are decoded on top of the standard ones, so omitted fields keep their default
+	rules := StandardRules()
+	req := CreateGameRequest{Rules: &rules}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	// 1. Fill in the defaults
+	config := TableConfig{Seed: time.Now().UnixNano(), Seats: MaxSeats, Rules: StandardRules()}
+	if req.Seed != nil {
+		config.Seed = *req.Seed
+	}
+	if req.Seats != 0 {
+		config.Seats = req.Seats
+	}
+	if req.Rules != nil {
+		config.Rules = *req.Rules
+	}
+
+	// 2. Open the table
+	table, err := s.Lobby.Create(config)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, CreateGameResponse{TableInfo: table.Info(), Token: table.CreatorToken()})
+}
+
+// handleListGames lists the open tables, ?status=playing (or finished, abandoned, all) lists others
+func (s *Server) handleListGames(w http.ResponseWriter, r *http.Request) {
+	status := TableStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "":
+		status = TableOpen
+	case "all":
+		status = ""
+	}
+	respondWithJSON(w, http.StatusOK, s.Lobby.List(status))
+}
+
+func (s *Server) handleGetGame(w http.ResponseWriter, r *http.Request) {
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, table.Info())
+}
+
+type JoinRequest struct {
+	Seat int    `json:"seat"`
+	Name string `json:"name"`
+}
+
+func (s *Server) handleJoinGame(w http.ResponseWriter, r *http.Request) {
+	var req JoinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+
+	token, err := table.Join(req.Seat, req.Name)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"player_id": req.Seat,
+		"token":     token,
+		"game":      table.Info(),
+	})
+}
+
+func (s *Server) handleStartGame(w http.ResponseWriter, r *http.Request) {
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	if _, err := table.Authenticate(bearerToken(r)); err != nil {
+		respondWithError(w, err)
+		return
+	}
+
+	if err := table.Start(); err != nil {
+		respondWithError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, table.Info())
+}
+
+func (s *Server) handleAbandonGame(w http.ResponseWriter, r *http.Request) {
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	if _, err := table.Authenticate(bearerToken(r)); err != nil {
+		respondWithError(w, err)
+		return
+	}
+
+	if err := table.Abandon(); err != nil {
+		respondWithError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, table.Info())
+}
+
+func (s *Server) handleDeleteGame(w http.ResponseWriter, r *http.Request) {
+	table, err := s.table(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	// The creator or a seated player
+	if err := table.AuthorizeDelete(bearerToken(r)); err != nil {
+		respondWithError(w, err)
+		return
+	}
+
+	if err := s.Lobby.Delete(table.id); err != nil {
+		respondWithError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, success)
+}
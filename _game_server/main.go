@@ -1,38 +1,108 @@
 package _game_server
 
-import "net/http"
+import (
+	"net/http"
+	"os"
+)
 
 type GameSavestate struct {
-	ID int
+	ID    int
+	Seed  int64 // The board derives from this seed, deck, dice and steals from it mixed with Salt
+	Salt  int64 // Secret of the server, a known seed alone does not predict the game
+	Rules Rules
+
+	Round           int
+	Turn            int // Turns played so far (all players), cards remember it
+	Phase           Phase
+	TurnOrder       []int // Player IDs in seating order
+	CurrentPlayerID int   // Player whose turn it is
+
+	SetupOrder    []int // Snake order of the initial placement
+	SetupStep     int   // Index into SetupOrder
+	SetupCornerID int   // Settlement waiting for its opening road, -1 if none
 
-	Round       int
 	Board       *Board
 	Bank        *Bank
-	ActionCards []ActionCard
 	Players     map[int]*Player
+	DiceHistory []DiceRoll
+
+	PendingDiscards map[int]int        // Player ID -> cards to discard after a 7
+	TradeOffers     map[int]TradeOffer // Open offers of the active player by offer ID, cleared when the turn ends
+
+	Results *GameResults // Set once somebody won
+
+	LongestRoadTrophy Trophy // Who has the 2 points for the longest road (min 5)
+	LargestArmyTrophy Trophy // Who has the 2 points for the largest army (min 3)
 
-	CurrentLongestRoad int // Tracks the currently longest road length
-	LongestRoadOwnerID int // Tracks who currently has the 2 points
-	MaxRoadLength      int // The length to beat (min 5)
+	Events   []GameEvent
+	Commands []Command // Accepted actions, the state is the replay of these from the seed
 
-	CurrentLargestArmy int // Tracks the currently highest amount of knights
-	LargestArmyOwnerID int // Tracks who currently has the 2 points
-	MaxKnightsPlayed   int // The Knights to beat (min 3)
+	rng       Randomizer  // Every random decision of the game
+	undoStack []undoPoint // Snapshots before the undoable commands at the end of the log
 }
 
 func main() {
-	// Initialize your game
-	game := &GameSavestate{
-		Board: NewStandardBoard(),
-		Bank:  NewBank(),
+	// Started games are saved to CATAN_DATA_DIR (if set) and come back after a restart
+	// (the token secret is kept there as well, unless CATAN_TOKEN_SECRET is set)
+	var store GameStore
+	dir := os.Getenv(DataDirEnv)
+	if dir != "" {
+		fileStore, err := NewFileStore(dir)
+		if err != nil {
+			panic(err)
+		}
+		store = fileStore
 	}
-	server := &Server{GameSaveState: game}
+	signer, err := LoadTokenSigner(dir)
+	if err != nil {
+		panic(err)
+	}
+	lobby := NewLobby(signer, store)
+	if err := lobby.Restore(); err != nil {
+		panic(err)
+	}
+	server := &Server{Lobby: lobby}
+	mux := http.NewServeMux()
+
+	// Lobby
+	mux.HandleFunc("POST /games", server.handleCreateGame)
+	mux.HandleFunc("GET /games", server.handleListGames)
+	mux.HandleFunc("GET /games/{id}", server.handleGetGame)
+	mux.HandleFunc("DELETE /games/{id}", server.handleDeleteGame)
+	mux.HandleFunc("POST /games/{id}/join", server.handleJoinGame)
+	mux.HandleFunc("POST /games/{id}/start", server.handleStartGame)
+	mux.HandleFunc("POST /games/{id}/abandon", server.handleAbandonGame)
+
+	// Game actions, scoped by game ID
+	mux.HandleFunc("POST /games/{id}/roll", server.handleRollDice)
+	mux.HandleFunc("POST /games/{id}/discard", server.handleDiscard)
+	mux.HandleFunc("POST /games/{id}/robber", server.handleMoveRobber)
+	mux.HandleFunc("GET /games/{id}/robber/victims", server.handleRobberVictims)
+	mux.HandleFunc("POST /games/{id}/build/settlement", server.handleBuildSettlement)
+	mux.HandleFunc("POST /games/{id}/build/city", server.handleBuildCity)
+	mux.HandleFunc("POST /games/{id}/build/road", server.handleBuildRoad)
+	mux.HandleFunc("POST /games/{id}/trade/maritime", server.handleMaritimeTrade)
+	mux.HandleFunc("POST /games/{id}/trade/offer", server.handleProposeTrade)
+	mux.HandleFunc("POST /games/{id}/trade/accept", server.handleAcceptTrade)
+	mux.HandleFunc("POST /games/{id}/cards/buy", server.handleBuyActionCard)
+	mux.HandleFunc("POST /games/{id}/cards/knight", server.handlePlayKnight)
+	mux.HandleFunc("POST /games/{id}/cards/year-of-plenty", server.handlePlayYearOfPlenty)
+	mux.HandleFunc("POST /games/{id}/cards/monopoly", server.handlePlayMonopoly)
+	mux.HandleFunc("POST /games/{id}/cards/road-building", server.handlePlayRoadBuilding)
+	mux.HandleFunc("POST /games/{id}/end-turn", server.handleEndTurn)
+	mux.HandleFunc("GET /games/{id}/status", server.handleGetStatus)
+	mux.HandleFunc("GET /games/{id}/stats/dice", server.handleDiceStats)
+	mux.HandleFunc("GET /games/{id}/results", server.handleGetResults)
+
+	// Command log
+	mux.HandleFunc("POST /games/{id}/undo", server.handleUndo)
+	mux.HandleFunc("GET /games/{id}/log", server.handleGetLog)
+	mux.HandleFunc("GET /games/{id}/replay", server.handleReplay)
 
-	// Routes
-	http.HandleFunc("/roll", server.handleRollDice)
-	http.HandleFunc("/build/settlement", server.handleBuildSettlement)
-	http.HandleFunc("/status", server.handleGetStatus)
+	// Event streams
+	mux.HandleFunc("GET /games/{id}/ws", server.handleEventSocket)
+	mux.HandleFunc("GET /games/{id}/events", server.handleEventFeed)
 
 	println("Catan Backend running on :8080")
-	http.ListenAndServe(":8080", nil)
+	http.ListenAndServe(":8080", mux)
 }
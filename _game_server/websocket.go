@@ -0,0 +1,237 @@
+package _game_server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------------------------------
+// WebSocket (RFC 6455)
+/*
+	Just enough of the protocol to push events: the opening handshake,
+	unmasked text frames from the server and control frames (close, ping,
+	pong). Data frames sent by the client are read and ignored.
+*/
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes (RFC 6455 section 5.2)
+const (
+	wsText  byte = 0x1
+	wsClose byte = 0x8
+	wsPing  byte = 0x9
+	wsPong  byte = 0xA
+)
+
+// Close codes (RFC 6455 section 7.4.1)
+const (
+	wsCloseNormal        = 1000
+	wsCloseGoingAway     = 1001
+	wsCloseProtocolError = 1002
+	wsCloseTooBig        = 1009
+)
+
+const (
+	wsMaxFrame     = 1 << 16 // Largest frame accepted from a client
+	wsWriteTimeout = 10 * time.Second
+)
+
+var errWebSocketProtocol = errors.New("websocket protocol error")
+
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex // One writer at a time
+}
+
+// headerHas reports whether a comma separated header contains the token (case insensitive)
+func headerHas(h http.Header, name string, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// websocketAccept computes Sec-WebSocket-Accept for the key of the client
+func websocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// upgradeWebSocket runs the opening handshake. On failure the error response
+// has already been written and nil is returned.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) *wsConn {
+	// 1. Validate the request of the client
+	if r.Method != http.MethodGet || !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "WebSocket upgrade expected", http.StatusBadRequest)
+		return nil
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "Invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil
+	}
+
+	// 2. Take over the connection and switch protocols
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil
+	}
+
+	ws := &wsConn{conn: conn, rw: rw}
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n\r\n"
+	if err := ws.write(func() error { _, err := rw.WriteString(response); return err }); err != nil {
+		conn.Close()
+		return nil
+	}
+	return ws
+}
+
+// write runs fn with the connection locked and flushes the buffer
+func (ws *wsConn) write(fn func() error) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := fn(); err != nil {
+		return err
+	}
+	return ws.rw.Flush()
+}
+
+// writeFrame sends one unfragmented frame, server frames are not masked
+func (ws *wsConn) writeFrame(opcode byte, payload []byte) error {
+	return ws.write(func() error {
+		header := []byte{0x80 | opcode, 0}
+		switch n := len(payload); {
+		case n < 126:
+			header[1] = byte(n)
+		case n <= 0xFFFF:
+			header[1] = 126
+			header = binary.BigEndian.AppendUint16(header, uint16(n))
+		default:
+			header[1] = 127
+			header = binary.BigEndian.AppendUint64(header, uint64(n))
+		}
+		if _, err := ws.rw.Write(header); err != nil {
+			return err
+		}
+		_, err := ws.rw.Write(payload)
+		return err
+	})
+}
+
+func (ws *wsConn) writeJSON(v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.writeFrame(wsText, payload)
+}
+
+func (ws *wsConn) writeClose(code int) error {
+	return ws.writeFrame(wsClose, binary.BigEndian.AppendUint16(nil, uint16(code)))
+}
+
+// readFrame reads one frame of the client and unmasks its payload
+func (ws *wsConn) readFrame() (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(ws.rw, header); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	// 1. Extended payload length
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(ws.rw, ext); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(ws.rw, ext); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	// 2. Clients must mask every frame, control frames are short
+	if !masked || (opcode >= wsClose && length > 125) {
+		ws.writeClose(wsCloseProtocolError)
+		return 0, nil, errWebSocketProtocol
+	}
+	if length > wsMaxFrame {
+		ws.writeClose(wsCloseTooBig)
+		return 0, nil, errWebSocketProtocol
+	}
+
+	// 3. Payload
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(ws.rw, mask); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// readLoop answers pings and returns when the client closes or the connection breaks
+func (ws *wsConn) readLoop() {
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsClose:
+			// Echo the status code of the client (if any) and stop
+			if len(payload) >= 2 {
+				ws.writeFrame(wsClose, payload[:2])
+			} else {
+				ws.writeClose(wsCloseNormal)
+			}
+			return
+		case wsPing:
+			if err := ws.writeFrame(wsPong, payload); err != nil {
+				return
+			}
+		}
+	}
+}